@@ -112,6 +112,47 @@ func Fatalf(msg string, args ...interface{}) {
 	log.Fatalln(fmt.Sprintf(msg, args...))
 }
 
+// Logger is a leveled logger. The package level functions are exposed as a Logger through Std.
+type Logger interface {
+	Debugf(msg string, args ...interface{})
+	Infof(msg string, args ...interface{})
+	Warnf(msg string, args ...interface{})
+	Errorf(msg string, args ...interface{})
+}
+
+type stdLogger struct{}
+
+// Std is a Logger backed by the package level loggers.
+var Std Logger = stdLogger{}
+
+func (stdLogger) Debugf(msg string, args ...interface{}) {
+	if env == Development {
+		logDebug.Println(fmt.Sprintf(msg, args...))
+	}
+}
+
+func (stdLogger) Infof(msg string, args ...interface{}) {
+	logInfo.Println(fmt.Sprintf(msg, args...))
+}
+
+func (stdLogger) Warnf(msg string, args ...interface{}) {
+	logWarn.Println(fmt.Sprintf(msg, args...))
+}
+
+func (stdLogger) Errorf(msg string, args ...interface{}) {
+	logErr.Println(fmt.Sprintf(msg, args...))
+}
+
+type nopLogger struct{}
+
+// Nop is a Logger that discards everything.
+var Nop Logger = nopLogger{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
+
 // Query logs a query statement with a debugf log level.
 func Query(query string, args ...interface{}) {
 	if env == Development {
@@ -1,36 +1,41 @@
 package platform
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-
-	log "github.com/SachinMeier/platform-client-go/pkg/log"
 )
 
 type AccountSummary struct {
 	Id               string `json:"id"`
-	Balance          sats   `json:"balance"`
-	AvailableBalance sats   `json:"available_balance"`
+	Balance          Amount `json:"balance"`
+	AvailableBalance Amount `json:"available_balance"`
 }
 
-// ReservedBalance returns the reserved balance of an account, calculated as Balance - AvailableBalance
-func (as *AccountSummary) ReservedBalance() sats {
-	return as.Balance - as.AvailableBalance
+// ReservedBalance returns the reserved balance of an account, calculated as Balance - AvailableBalance,
+// or ErrAmountOverflow
+func (as *AccountSummary) ReservedBalance() (Amount, error) {
+	return as.Balance.Sub(as.AvailableBalance)
 }
 
 // AccountBalance returns a summary of the account's balance and available balance
 func (pc *PlatformClient) AccountBalance() (AccountSummary, error) {
-	log.Info("Querying Account Balance")
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/accounts/%s/", pc.BaseURL, pc.accountId), nil)
+	return pc.AccountBalanceContext(pc.Context)
+}
+
+// AccountBalanceContext is AccountBalance bound to ctx
+func (pc *PlatformClient) AccountBalanceContext(ctx context.Context, opts ...CallOption) (AccountSummary, error) {
+	pc.logger.Infof("Querying Account Balance")
+	req, err := http.NewRequestWithContext(pc.callContext(ctx), "GET", fmt.Sprintf("%s/accounts/%s/", pc.BaseURL, pc.accountId), nil)
 	if err != nil {
-		log.Error("Internal Error")
+		pc.logger.Errorf("Internal Error")
 		return AccountSummary{}, err
 	}
 
 	var acct AccountSummary
-	err = pc.sendRequest(req, &acct)
+	err = pc.sendRequest(EndpointAccountBalance, req, &acct, opts...)
 	if err != nil {
-		log.Errorf("Account Query Failed: %s", err.Error())
+		pc.logger.Errorf("Account Query Failed: %s", err.Error())
 		return AccountSummary{}, err
 	}
 	return acct, nil
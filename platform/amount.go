@@ -0,0 +1,382 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Conversion factors between the units of an Amount
+const (
+	MsatPerSat = 1000
+	SatsPerBTC = 100000000
+	MsatPerBTC = MsatPerSat * SatsPerBTC
+)
+
+// ErrAmountOverflow is returned when an Amount does not fit in an int64 of millisatoshis
+var ErrAmountOverflow = errors.New("platform: amount overflow")
+
+// Unit is a unit an Amount is parsed from or formatted in
+type Unit string
+
+const (
+	UnitMsat Unit = "msat"
+	UnitSat  Unit = "sats"
+	UnitBTC  Unit = "BTC"
+)
+
+// unitMsats maps the unit names accepted by ParseAmount, lowercased, to their value in millisatoshis
+var unitMsats = map[string]int64{
+	"":              MsatPerSat,
+	"msat":          1,
+	"msats":         1,
+	"millisat":      1,
+	"millisats":     1,
+	"millisatoshi":  1,
+	"millisatoshis": 1,
+	"sat":           MsatPerSat,
+	"sats":          MsatPerSat,
+	"satoshi":       MsatPerSat,
+	"satoshis":      MsatPerSat,
+	"btc":           MsatPerBTC,
+	"bitcoin":       MsatPerBTC,
+	"bitcoins":      MsatPerBTC,
+}
+
+// magnitudes are the suffixes ParseAmount accepts on a number, as in "21k sats". They must be followed by a space
+// or end the amount, so that "1Msat" is a millisatoshi
+var magnitudes = map[byte]int64{
+	'k': 1000,
+	'K': 1000,
+	'M': 1000000,
+}
+
+// Amount is an amount of bitcoin with millisatoshi precision. The zero value is zero.
+// Platform API takes and returns whole satoshis, which is how an Amount is encoded in JSON.
+type Amount struct {
+	msat int64
+}
+
+// Sats returns an Amount of n satoshis. It saturates at the largest and smallest Amounts
+func Sats(n int64) Amount {
+	if n > math.MaxInt64/MsatPerSat {
+		return Amount{math.MaxInt64}
+	}
+	if n < math.MinInt64/MsatPerSat {
+		return Amount{math.MinInt64}
+	}
+	return Amount{n * MsatPerSat}
+}
+
+// Msats returns an Amount of n millisatoshis
+func Msats(n int64) Amount {
+	return Amount{n}
+}
+
+// ParseAmount parses an amount such as "0.0015 BTC", "21k sats", "2100 sat" or "1500000 msat".
+// A number without a unit is in satoshis. The units are case insensitive, and the number may carry
+// a k (thousand) or M (million) suffix. Amounts more precise than a millisatoshi are rejected.
+func ParseAmount(s string) (Amount, error) {
+	fail := func(reason string) (Amount, error) {
+		return Amount{}, fmt.Errorf("platform: invalid amount %q: %s", s, reason)
+	}
+	str := strings.TrimSpace(s)
+	end := 0
+	for end < len(str) && (str[end] == '-' || str[end] == '+' || str[end] == '.' || (str[end] >= '0' && str[end] <= '9')) {
+		end++
+	}
+	number := str[:end]
+	if number == "" {
+		return fail("no number")
+	}
+	scale := int64(1)
+	if m, ok := magnitudes[byteAt(str, end)]; ok && (end+1 == len(str) || str[end+1] == ' ') {
+		scale = m
+		end++
+	}
+	unit := strings.TrimSpace(str[end:])
+	perUnit, ok := unitMsats[strings.ToLower(unit)]
+	if !ok {
+		return fail(fmt.Sprintf("unknown unit %q", unit))
+	}
+	scale, ok = mulInt64(scale, perUnit)
+	if !ok {
+		return fail(ErrAmountOverflow.Error())
+	}
+
+	// the sign is kept on both parts so that the smallest Amount can be parsed
+	sign, digits := "", number
+	switch number[0] {
+	case '-':
+		sign, digits = "-", number[1:]
+	case '+':
+		digits = number[1:]
+	}
+	whole, frac, _ := cutString(digits, ".")
+	if (whole == "" && frac == "") || strings.ContainsAny(whole+frac, "+-.") {
+		return fail("malformed number")
+	}
+
+	var msat int64
+	if whole != "" {
+		n, err := strconv.ParseInt(sign+whole, 10, 64)
+		if err != nil {
+			return fail(ErrAmountOverflow.Error())
+		}
+		if msat, ok = mulInt64(n, scale); !ok {
+			return fail(ErrAmountOverflow.Error())
+		}
+	}
+	// the fraction is exact only if its digits, trailing zeros aside, are covered by scale
+	frac = strings.TrimRight(frac, "0")
+	if frac != "" {
+		divisor := int64(1)
+		for range frac {
+			if divisor, ok = mulInt64(divisor, 10); !ok || divisor > scale {
+				return fail("more precise than a millisatoshi")
+			}
+		}
+		if scale%divisor != 0 {
+			return fail("more precise than a millisatoshi")
+		}
+		n, err := strconv.ParseInt(sign+frac, 10, 64)
+		if err != nil {
+			return fail("malformed number")
+		}
+		part, ok := mulInt64(n, scale/divisor)
+		if !ok {
+			return fail(ErrAmountOverflow.Error())
+		}
+		if msat, ok = addInt64(msat, part); !ok {
+			return fail(ErrAmountOverflow.Error())
+		}
+	}
+	return Amount{msat}, nil
+}
+
+// MustParseAmount is ParseAmount for constants, panicking if s is invalid
+func MustParseAmount(s string) Amount {
+	a, err := ParseAmount(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Msats returns the Amount in millisatoshis
+func (a Amount) Msats() int64 {
+	return a.msat
+}
+
+// Sats returns the Amount in whole satoshis, truncated toward zero
+func (a Amount) Sats() int64 {
+	return a.msat / MsatPerSat
+}
+
+// WholeSats reports whether the Amount has no fraction of a satoshi, as Platform API requires
+func (a Amount) WholeSats() bool {
+	return a.msat%MsatPerSat == 0
+}
+
+// IsZero reports whether the Amount is zero
+func (a Amount) IsZero() bool {
+	return a.msat == 0
+}
+
+// Sign returns -1, 0 or 1 for a negative, zero or positive Amount
+func (a Amount) Sign() int {
+	switch {
+	case a.msat < 0:
+		return -1
+	case a.msat > 0:
+		return 1
+	}
+	return 0
+}
+
+// Cmp returns -1, 0 or 1 if a is less than, equal to or greater than b
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a.msat < b.msat:
+		return -1
+	case a.msat > b.msat:
+		return 1
+	}
+	return 0
+}
+
+// Add returns a + b, or ErrAmountOverflow
+func (a Amount) Add(b Amount) (Amount, error) {
+	sum, ok := addInt64(a.msat, b.msat)
+	if !ok {
+		return Amount{}, ErrAmountOverflow
+	}
+	return Amount{sum}, nil
+}
+
+// Sub returns a - b, or ErrAmountOverflow
+func (a Amount) Sub(b Amount) (Amount, error) {
+	diff, ok := subInt64(a.msat, b.msat)
+	if !ok {
+		return Amount{}, ErrAmountOverflow
+	}
+	return Amount{diff}, nil
+}
+
+// Mul returns a * n, or ErrAmountOverflow
+func (a Amount) Mul(n int64) (Amount, error) {
+	product, ok := mulInt64(a.msat, n)
+	if !ok {
+		return Amount{}, ErrAmountOverflow
+	}
+	return Amount{product}, nil
+}
+
+// String formats the Amount in satoshis, with the millisatoshis as decimals if any, such as "2100 sats" or "0.5 sats"
+func (a Amount) String() string {
+	return a.FormatUnit(UnitSat)
+}
+
+// FormatUnit formats the Amount in unit, such as "0.0015 BTC", "150000 sats" or "150000000 msat".
+// Decimals are written only as far as needed to be exact. Any other unit formats in sats
+func (a Amount) FormatUnit(unit Unit) string {
+	var perUnit int64
+	switch unit {
+	case UnitMsat:
+		perUnit = 1
+	case UnitBTC:
+		perUnit = MsatPerBTC
+	default:
+		unit, perUnit = UnitSat, MsatPerSat
+	}
+	return formatDecimal(a.msat, perUnit) + " " + string(unit)
+}
+
+// formatDecimal formats n / perUnit, a power of ten, exactly
+func formatDecimal(n, perUnit int64) string {
+	sign := ""
+	// work in uint64 so that the smallest int64 can be negated
+	u := uint64(n)
+	if n < 0 {
+		sign = "-"
+		u = uint64(-(n + 1)) + 1
+	}
+	p := uint64(perUnit)
+	s := sign + strconv.FormatUint(u/p, 10)
+	if frac := u % p; frac != 0 {
+		digits := len(strconv.FormatUint(p, 10)) - 1
+		s += "." + strings.TrimRight(fmt.Sprintf("%0*d", digits, frac), "0")
+	}
+	return s
+}
+
+// MarshalJSON encodes the Amount as a number of satoshis, as Platform API expects.
+// An Amount with a fraction of a satoshi cannot be sent and fails with ErrInvalidRequest
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if !a.WholeSats() {
+		return nil, invalid("%s is not a whole number of sats", a)
+	}
+	return []byte(strconv.FormatInt(a.Sats(), 10)), nil
+}
+
+// UnmarshalJSON decodes a number of satoshis, which may have up to three decimals or an exponent
+// as in 2.1e3, or a string accepted by ParseAmount. null leaves the Amount unchanged
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	} else if bytes.ContainsAny(b, "eE") {
+		return a.unmarshalExponent(s)
+	}
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// unmarshalExponent decodes a JSON number of satoshis in exponent form, which must be exact to the millisatoshi
+func (a *Amount) unmarshalExponent(s string) error {
+	var n json.Number
+	if err := json.Unmarshal([]byte(s), &n); err != nil {
+		return fmt.Errorf("platform: invalid amount %q", s)
+	}
+	value, ok := new(big.Rat).SetString(n.String())
+	if !ok {
+		return fmt.Errorf("platform: invalid amount %q", s)
+	}
+	value.Mul(value, new(big.Rat).SetInt64(MsatPerSat))
+	if !value.IsInt() {
+		return fmt.Errorf("platform: invalid amount %q: more precise than a millisatoshi", s)
+	}
+	if !value.Num().IsInt64() {
+		return fmt.Errorf("platform: invalid amount %q: %s", s, ErrAmountOverflow.Error())
+	}
+	*a = Amount{value.Num().Int64()}
+	return nil
+}
+
+// MarshalText formats the Amount as String does, so that it can be used in configuration files
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText parses the Amount with ParseAmount
+func (a *Amount) UnmarshalText(b []byte) error {
+	parsed, err := ParseAmount(string(b))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// byteAt returns s[i], or 0 past the end of s
+func byteAt(s string, i int) byte {
+	if i >= len(s) {
+		return 0
+	}
+	return s[i]
+}
+
+// addInt64 returns a + b and whether it did not overflow
+func addInt64(a, b int64) (int64, bool) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, false
+	}
+	return sum, true
+}
+
+// subInt64 returns a - b and whether it did not overflow
+func subInt64(a, b int64) (int64, bool) {
+	diff := a - b
+	if (b > 0 && diff > a) || (b < 0 && diff < a) {
+		return 0, false
+	}
+	return diff, true
+}
+
+// mulInt64 returns a * b and whether it did not overflow
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
@@ -0,0 +1,351 @@
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redacted replaces the values of redacted fields in audit records and wire dumps
+const redacted = "[REDACTED]"
+
+// DefaultAuditRedactedFields are the JSON fields whose values are never written to an AuditLog, at any depth
+var DefaultAuditRedactedFields = []string{"secret", "api_key", "api_secret", "password", "token", "access_token", "preimage"}
+
+// ErrAuditChain is matched by the AuditChainError returned when an audit log fails verification
+var ErrAuditChain = errors.New("platform: audit chain broken")
+
+// AuditRecord is one line of an AuditLog, describing one mutating call
+type AuditRecord struct {
+	// Seq numbers the records of a log from 1
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	// DurationMicros is how long the call took, retries included
+	DurationMicros int64 `json:"duration_us"`
+	// Actor is who made the call, as set by ContextWithAuditActor
+	Actor          string          `json:"actor,omitempty"`
+	Account        string          `json:"account"`
+	Endpoint       Endpoint        `json:"endpoint"`
+	Method         string          `json:"method"`
+	URL            string          `json:"url"`
+	IdempotencyKey string          `json:"idempotency_key,omitempty"`
+	Attempts       int             `json:"attempts"`
+	Request        json.RawMessage `json:"request,omitempty"`
+	StatusCode     int             `json:"status,omitempty"`
+	Response       json.RawMessage `json:"response,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	// PrevHash is the Hash of the previous record, empty for the first one
+	PrevHash string `json:"prev_hash"`
+	// Hash is the hex SHA-256 of the record encoded without its Hash
+	Hash string `json:"hash,omitempty"`
+}
+
+// hash returns the hash of r, which covers every field but Hash
+func (r AuditRecord) hash() (string, error) {
+	r.Hash = ""
+	b, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditChainError is returned by VerifyAuditLog for the first line that breaks the chain
+type AuditChainError struct {
+	// Line is the line number, from 1
+	Line   int
+	Reason string
+}
+
+func (e *AuditChainError) Error() string {
+	return fmt.Sprintf("platform: audit chain broken at line %d: %s", e.Line, e.Reason)
+}
+
+func (e *AuditChainError) Is(target error) bool {
+	return target == ErrAuditChain
+}
+
+type auditActorKey struct{}
+
+// ContextWithAuditActor returns a copy of ctx whose calls are recorded as made by actor, such as an operator or a job
+func ContextWithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditLog appends a hash-chained JSON line for every mutating call of the clients it is passed to.
+// Each record carries the hash of the previous one, so VerifyAuditLog detects removed, reordered or edited lines.
+// Calls of a client created WithDryRun are not recorded, as they are never made.
+// A log fails closed: once writing a record fails, mutating calls of its clients fail with Err before they are made,
+// so that no money moves unrecorded. A record is written when its call returns, so a call cut short by a crash has none.
+type AuditLog struct {
+	redact map[string]bool
+
+	mu   sync.Mutex
+	w    io.Writer
+	file *os.File
+	seq  uint64
+	prev string
+	err  error
+}
+
+// NewAuditLog returns an AuditLog starting a new chain in w. fields are redacted in addition to DefaultAuditRedactedFields
+func NewAuditLog(w io.Writer, fields ...string) *AuditLog {
+	redact := make(map[string]bool, len(DefaultAuditRedactedFields)+len(fields))
+	for _, f := range append(DefaultAuditRedactedFields, fields...) {
+		redact[strings.ToLower(f)] = true
+	}
+	return &AuditLog{w: w, redact: redact}
+}
+
+// OpenAuditLog verifies the audit log at path, creating it if missing, and returns an AuditLog appending to its chain.
+// fields are redacted in addition to DefaultAuditRedactedFields
+func OpenAuditLog(path string, fields ...string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("platform: opening audit log: %w", err)
+	}
+	last, _, err := verifyAuditLog(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	al := NewAuditLog(f, fields...)
+	al.file = f
+	if last != nil {
+		al.seq = last.Seq
+		al.prev = last.Hash
+	}
+	return al, nil
+}
+
+// WithAuditLog records the mutating calls of the client in al
+func WithAuditLog(al *AuditLog) Option {
+	return func(o *options) error {
+		if al == nil {
+			return errors.New("platform: nil audit log")
+		}
+		o.auditLog = al
+		return nil
+	}
+}
+
+// Err returns the first error writing a record. Once it fails an AuditLog writes no more records,
+// and the mutating calls of its clients return the error without being made
+func (al *AuditLog) Err() error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return al.err
+}
+
+// Close closes the file of an AuditLog created by OpenAuditLog
+func (al *AuditLog) Close() error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if al.file == nil {
+		return nil
+	}
+	err := al.file.Close()
+	al.file = nil
+	if al.err == nil {
+		al.err = errors.New("platform: audit log closed")
+	}
+	return err
+}
+
+// middleware records every mutating call passing through it, refusing them once the log has failed
+func (al *AuditLog) middleware(pc *PlatformClient) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			if !call.Endpoint.Mutating() {
+				return next(ctx, call)
+			}
+			if err := al.Err(); err != nil {
+				pc.logger.Errorf("Audit Log Unavailable, %s Refused: %s", call.Endpoint, err.Error())
+				return err
+			}
+			start := time.Now()
+			err := next(ctx, call)
+			if recErr := al.record(ctx, call, start, time.Since(start), err); recErr != nil {
+				pc.logger.Errorf("Audit Log Failed, %s Not Recorded: %s", call.Endpoint, recErr.Error())
+			}
+			return err
+		}
+	}
+}
+
+// record appends the record of a call, returning the error of the log if it was not recorded
+func (al *AuditLog) record(ctx context.Context, call *Call, start time.Time, elapsed time.Duration, callErr error) error {
+	req := call.Request
+	rec := AuditRecord{
+		Time:           start.UTC(),
+		DurationMicros: elapsed.Microseconds(),
+		Account:        call.AccountID,
+		Endpoint:       call.Endpoint,
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		IdempotencyKey: req.Header.Get(IdempotencyKeyHeader),
+		Attempts:       call.Attempts,
+	}
+	if actor, ok := ctx.Value(auditActorKey{}).(string); ok {
+		rec.Actor = actor
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := io.ReadAll(body)
+			body.Close()
+			rec.Request = al.redactBody(b)
+		}
+	}
+	if call.Response != nil {
+		rec.StatusCode = call.Response.StatusCode
+	}
+	var apiErr *APIError
+	switch {
+	case callErr == nil:
+		rec.Response = al.redactBody(call.body)
+	case errors.As(callErr, &apiErr):
+		rec.Response = al.redactBody(apiErr.Body)
+	}
+	if callErr != nil {
+		rec.Error = callErr.Error()
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if al.err != nil {
+		return al.err
+	}
+	rec.Seq = al.seq + 1
+	rec.PrevHash = al.prev
+	hash, err := rec.hash()
+	if err != nil {
+		al.err = err
+		return err
+	}
+	rec.Hash = hash
+	line, err := json.Marshal(rec)
+	if err != nil {
+		al.err = err
+		return err
+	}
+	if _, err := al.w.Write(append(line, '\n')); err != nil {
+		al.err = fmt.Errorf("platform: writing audit log: %w", err)
+		return al.err
+	}
+	if al.file != nil {
+		if err := al.file.Sync(); err != nil {
+			al.err = fmt.Errorf("platform: syncing audit log: %w", err)
+			return al.err
+		}
+	}
+	al.seq = rec.Seq
+	al.prev = hash
+	return nil
+}
+
+// redactBody returns a JSON body with the values of redacted fields replaced.
+// A body that is not JSON is kept as a JSON string
+func (al *AuditLog) redactBody(body []byte) json.RawMessage {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if b, ok := redactJSON(body, al.redact); ok {
+		return b
+	}
+	b, _ := json.Marshal(string(body))
+	return b
+}
+
+// redactJSON returns a JSON body with the values of fields, matched case insensitively at any depth, replaced.
+// It reports false if body is not JSON
+func redactJSON(body []byte, fields map[string]bool) ([]byte, bool) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	b, err := json.Marshal(redactValue(v, fields))
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+func redactValue(v interface{}, fields map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if fields[strings.ToLower(key)] {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(value, fields)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value, fields)
+		}
+	}
+	return v
+}
+
+// VerifyAuditLog checks the hash chain of an audit log and returns the number of records in it.
+// A missing, reordered, inserted or edited record fails with an AuditChainError.
+// Truncating the newest records cannot be detected from the log alone, compare the returned count
+// or the last hash with a copy kept elsewhere for that.
+func VerifyAuditLog(r io.Reader) (int, error) {
+	_, n, err := verifyAuditLog(r)
+	return n, err
+}
+
+// verifyAuditLog checks the hash chain of an audit log and returns its last record
+func verifyAuditLog(r io.Reader) (*AuditRecord, int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), int(DefaultMaxResponseBytes)*2)
+	var last *AuditRecord
+	n := 0
+	for scanner.Scan() {
+		n++
+		chainErr := func(format string, args ...interface{}) error {
+			return &AuditChainError{Line: n, Reason: fmt.Sprintf(format, args...)}
+		}
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return last, n - 1, chainErr("invalid record: %s", err.Error())
+		}
+		wantSeq, wantPrev := uint64(1), ""
+		if last != nil {
+			wantSeq, wantPrev = last.Seq+1, last.Hash
+		}
+		if rec.Seq != wantSeq {
+			return last, n - 1, chainErr("sequence %d, want %d", rec.Seq, wantSeq)
+		}
+		if rec.PrevHash != wantPrev {
+			return last, n - 1, chainErr("previous hash does not match record %d", wantSeq-1)
+		}
+		hash, err := rec.hash()
+		if err != nil {
+			return last, n - 1, chainErr("%s", err.Error())
+		}
+		if rec.Hash != hash {
+			return last, n - 1, chainErr("record %d was modified", rec.Seq)
+		}
+		last = &rec
+	}
+	if err := scanner.Err(); err != nil {
+		return last, n, fmt.Errorf("platform: reading audit log: %w", err)
+	}
+	return last, n, nil
+}
@@ -0,0 +1,296 @@
+package platform
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTokenExpiryDelta is how long before its expiry an OAuth2 token is refreshed
+// when OAuth2Config.ExpiryDelta is not set
+const DefaultTokenExpiryDelta = 30 * time.Second
+
+// Authenticator sets the credentials of a request to Platform API.
+// It is called once per call, and must be safe for concurrent use.
+type Authenticator interface {
+	Authenticate(ctx context.Context, req *http.Request) error
+}
+
+// AuthenticatorFunc adapts a function to an Authenticator
+type AuthenticatorFunc func(ctx context.Context, req *http.Request) error
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, req *http.Request) error {
+	return f(ctx, req)
+}
+
+// basicAuth authenticates with an API secret as Platform API expects it
+type basicAuth struct {
+	credential string
+}
+
+// BasicAuth returns the Authenticator used by WithAccount, sending apiKey:apiKey as basic credentials
+func BasicAuth(apiKey string) Authenticator {
+	return &basicAuth{credential: createCredential(apiKey)}
+}
+
+func (ba *basicAuth) Authenticate(_ context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", fmt.Sprintf("basic %s", ba.credential))
+	return nil
+}
+
+// createCredential creates the basic auth credential used to authenticate requests to Platform API
+func createCredential(apiKey string) string {
+	key := fmt.Sprintf("%s:%s", apiKey, apiKey)
+	return b64.StdEncoding.EncodeToString([]byte(key))
+}
+
+// bearerToken authenticates with a static token
+type bearerToken struct {
+	token string
+}
+
+// BearerToken returns an Authenticator sending token as a bearer token
+func BearerToken(token string) Authenticator {
+	return &bearerToken{token: token}
+}
+
+func (bt *bearerToken) Authenticate(_ context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+bt.token)
+	return nil
+}
+
+// WithAuthenticator sets how requests are authenticated, replacing the basic credentials of WithAccount.
+// The account id must still be set, with WithAccount or WithAccountID.
+func WithAuthenticator(auth Authenticator) Option {
+	return func(o *options) error {
+		if auth == nil {
+			return errors.New("platform: nil authenticator")
+		}
+		o.authenticator = auth
+		return nil
+	}
+}
+
+// WithAccountID sets the River account without an API secret, for clients authenticated by WithAuthenticator
+func WithAccountID(accountId string) Option {
+	return func(o *options) error {
+		if strings.TrimSpace(accountId) == "" {
+			return errors.New("platform: empty account id")
+		}
+		o.accountId = accountId
+		return nil
+	}
+}
+
+// OAuth2Config configures an OAuth2 client credentials flow
+type OAuth2Config struct {
+	// TokenURL is the token endpoint of the authorization server
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// HTTPClient sends token requests. http.DefaultClient is used when nil
+	HTTPClient *http.Client
+	// ExpiryDelta is how long before its expiry a token is refreshed. Defaults to DefaultTokenExpiryDelta
+	ExpiryDelta time.Duration
+}
+
+// TokenError is returned when the token endpoint refuses to issue a token
+type TokenError struct {
+	StatusCode int
+	// Code is the OAuth2 error code, such as invalid_client
+	Code        string
+	Description string
+	Body        []byte
+}
+
+func (e *TokenError) Error() string {
+	msg := e.Code
+	if e.Description != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Description)
+	}
+	if msg == "" {
+		msg = snippet(e.Body)
+	}
+	return fmt.Sprintf("platform: token request failed with status %d: %s", e.StatusCode, msg)
+}
+
+// Is makes errors.Is(err, ErrUnauthorized) true when the client credentials were rejected
+func (e *TokenError) Is(target error) bool {
+	return target == ErrUnauthorized &&
+		(e.StatusCode == http.StatusUnauthorized || e.Code == "invalid_client" || e.Code == "unauthorized_client")
+}
+
+// tokenResponse is the successful response of a token endpoint
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// tokenErrorResponse is the error response of a token endpoint
+type tokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// OAuth2Authenticator authenticates with access tokens obtained through the OAuth2 client credentials flow.
+// Tokens are cached until shortly before they expire, and concurrent calls share one token request.
+// A call rejected with 401 Unauthorized is retried once with a new token.
+type OAuth2Authenticator struct {
+	config OAuth2Config
+	now    func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expiry  time.Time
+	pending chan struct{}
+	err     error
+}
+
+// NewOAuth2Authenticator validates config and returns an Authenticator for it. No token is requested until the first call
+func NewOAuth2Authenticator(config OAuth2Config) (*OAuth2Authenticator, error) {
+	u, err := url.Parse(config.TokenURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("platform: invalid token URL %q", config.TokenURL)
+	}
+	if config.ClientID == "" {
+		return nil, errors.New("platform: empty oauth2 client id")
+	}
+	if config.ExpiryDelta < 0 {
+		return nil, fmt.Errorf("platform: token expiry delta must not be negative, got %s", config.ExpiryDelta)
+	}
+	if config.ExpiryDelta == 0 {
+		config.ExpiryDelta = DefaultTokenExpiryDelta
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	return &OAuth2Authenticator{config: config, now: time.Now}, nil
+}
+
+func (oa *OAuth2Authenticator) Authenticate(ctx context.Context, req *http.Request) error {
+	token, err := oa.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Token returns the cached access token, requesting a new one if it is missing or about to expire
+func (oa *OAuth2Authenticator) Token(ctx context.Context) (string, error) {
+	for {
+		oa.mu.Lock()
+		if oa.token != "" && oa.now().Add(oa.config.ExpiryDelta).Before(oa.expiry) {
+			token := oa.token
+			oa.mu.Unlock()
+			return token, nil
+		}
+		if pending := oa.pending; pending != nil {
+			oa.mu.Unlock()
+			select {
+			case <-pending:
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+			oa.mu.Lock()
+			err := oa.err
+			oa.mu.Unlock()
+			// a refresh cut short by its caller's context is retried by the next caller
+			if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+				return "", err
+			}
+			continue
+		}
+		pending := make(chan struct{})
+		oa.pending = pending
+		oa.mu.Unlock()
+
+		token, expiry, err := oa.fetch(ctx)
+
+		oa.mu.Lock()
+		oa.pending = nil
+		oa.err = err
+		if err == nil {
+			oa.token = token
+			oa.expiry = expiry
+		}
+		oa.mu.Unlock()
+		close(pending)
+		return token, err
+	}
+}
+
+// Invalidate drops the cached token so that the next call requests a new one
+func (oa *OAuth2Authenticator) Invalidate() {
+	oa.mu.Lock()
+	defer oa.mu.Unlock()
+	oa.token = ""
+}
+
+// Refresh drops the cached token. It is called when Platform API rejects the token
+func (oa *OAuth2Authenticator) Refresh(context.Context) error {
+	oa.Invalidate()
+	return nil
+}
+
+// fetch requests a token from the token endpoint
+func (oa *OAuth2Authenticator) fetch(ctx context.Context) (string, time.Time, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(oa.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(oa.config.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, oa.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// RFC 6749 section 2.3.1 form-encodes the client credentials before basic encoding them
+	req.SetBasicAuth(url.QueryEscape(oa.config.ClientID), url.QueryEscape(oa.config.ClientSecret))
+
+	requested := oa.now()
+	res, err := oa.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("platform: token request failed: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := readBody(res.Body, DefaultMaxResponseBytes)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("platform: reading token response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		tokenErr := &TokenError{StatusCode: res.StatusCode, Body: body}
+		var ter tokenErrorResponse
+		if json.Unmarshal(body, &ter) == nil {
+			tokenErr.Code = ter.Error
+			tokenErr.Description = ter.ErrorDescription
+		}
+		return "", time.Time{}, tokenErr
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return "", time.Time{}, fmt.Errorf("platform: decoding token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return "", time.Time{}, errors.New("platform: token response has no access token")
+	}
+	if tr.TokenType != "" && !strings.EqualFold(tr.TokenType, "bearer") {
+		return "", time.Time{}, fmt.Errorf("platform: unsupported token type %q", tr.TokenType)
+	}
+	// a token without expires_in is kept for an hour
+	expiresIn := time.Hour
+	if tr.ExpiresIn > 0 {
+		expiresIn = time.Duration(tr.ExpiresIn) * time.Second
+	}
+	return tr.AccessToken, requested.Add(expiresIn), nil
+}
@@ -0,0 +1,52 @@
+package platform
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// CallOption configures a single call to Platform API
+type CallOption func(*callOptions)
+
+// callOptions collects the CallOptions passed to one call
+type callOptions struct {
+	timeout        time.Duration
+	header         http.Header
+	idempotencyKey string
+	meta           *ResponseMeta
+}
+
+func newCallOptions(opts []CallOption) *callOptions {
+	co := &callOptions{
+		header: http.Header{},
+	}
+	for _, opt := range opts {
+		opt(co)
+	}
+	return co
+}
+
+// WithCallTimeout bounds a single call, including reading its response.
+// It is applied on top of the call's Context and the http.Client timeout.
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return func(co *callOptions) {
+		co.timeout = timeout
+	}
+}
+
+// WithHeader adds an extra header to the request of a single call.
+// Headers set by the client itself, such as Authorization, cannot be overridden.
+func WithHeader(key, value string) CallOption {
+	return func(co *callOptions) {
+		co.header.Add(key, value)
+	}
+}
+
+// callContext returns ctx, or the client's default Context if ctx is nil
+func (pc *PlatformClient) callContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return pc.Context
+	}
+	return ctx
+}
@@ -0,0 +1,252 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting Platform API while the circuit breaker is open
+var ErrCircuitOpen = errors.New("platform: circuit breaker is open")
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState int
+
+const (
+	// StateClosed lets every request through and counts failures
+	StateClosed CircuitState = iota
+	// StateOpen rejects every request with ErrCircuitOpen until the cool-down has passed
+	StateOpen
+	// StateHalfOpen lets a few probe requests through to decide whether to close or open again
+	StateHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreakerSettings configures a CircuitBreaker
+type CircuitBreakerSettings struct {
+	// Window is the interval over which requests are counted while closed
+	Window time.Duration
+	// MinRequests is the number of requests in a Window before FailureRatio is considered
+	MinRequests int
+	// FailureRatio opens the circuit when failures/requests in a Window reaches it
+	FailureRatio float64
+	// CoolDown is how long the circuit stays open before letting probes through
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of probes that must succeed to close the circuit
+	HalfOpenRequests int
+	// OnStateChange, if set, is called after every transition, from the goroutine whose request caused it
+	OnStateChange func(from, to CircuitState)
+}
+
+// DefaultCircuitBreakerSettings opens after half of at least 10 requests in a minute fail, and probes after 30s
+func DefaultCircuitBreakerSettings() CircuitBreakerSettings {
+	return CircuitBreakerSettings{
+		Window:           time.Minute,
+		MinRequests:      10,
+		FailureRatio:     0.5,
+		CoolDown:         30 * time.Second,
+		HalfOpenRequests: 1,
+	}
+}
+
+func (s *CircuitBreakerSettings) validate() error {
+	if s.Window <= 0 || s.CoolDown <= 0 {
+		return errors.New("platform: circuit breaker window and cool-down must be positive")
+	}
+	if s.MinRequests < 1 || s.HalfOpenRequests < 1 {
+		return errors.New("platform: circuit breaker needs at least 1 request and 1 probe")
+	}
+	if s.FailureRatio <= 0 || s.FailureRatio > 1 {
+		return errors.New("platform: circuit breaker failure ratio must be in (0, 1]")
+	}
+	return nil
+}
+
+// CircuitBreaker stops sending requests to Platform API while it keeps failing.
+// Transport errors and 5xx responses count as failures. A CircuitBreaker is safe
+// for concurrent use and may be shared between clients.
+type CircuitBreaker struct {
+	settings CircuitBreakerSettings
+
+	mu          sync.Mutex
+	state       CircuitState
+	generation  uint64
+	windowStart time.Time
+	openedAt    time.Time
+	requests    int
+	failures    int
+	probes      int
+	successes   int
+	// transitions not yet passed to OnStateChange
+	pending []transition
+}
+
+type transition struct {
+	from, to CircuitState
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker
+func NewCircuitBreaker(settings CircuitBreakerSettings) (*CircuitBreaker, error) {
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+	return &CircuitBreaker{
+		settings:    settings,
+		windowStart: time.Now(),
+	}, nil
+}
+
+// WithCircuitBreaker guards every request of the client with cb
+func WithCircuitBreaker(cb *CircuitBreaker) Option {
+	return func(o *options) error {
+		if cb == nil {
+			return errors.New("platform: nil circuit breaker")
+		}
+		o.circuitBreaker = cb
+		return nil
+	}
+}
+
+// State returns the current state of the circuit
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.notify()
+	defer cb.mu.Unlock()
+	cb.tick(time.Now())
+	return cb.state
+}
+
+// allow admits a request, returning the generation to report its outcome with, or ErrCircuitOpen
+func (cb *CircuitBreaker) allow() (uint64, error) {
+	cb.mu.Lock()
+	defer cb.notify()
+	defer cb.mu.Unlock()
+	cb.tick(time.Now())
+	switch cb.state {
+	case StateOpen:
+		return 0, ErrCircuitOpen
+	case StateHalfOpen:
+		if cb.probes >= cb.settings.HalfOpenRequests {
+			return 0, ErrCircuitOpen
+		}
+		cb.probes++
+	}
+	cb.requests++
+	return cb.generation, nil
+}
+
+// cancel releases a request admitted by allow that ended without an outcome, such as a canceled Context
+func (cb *CircuitBreaker) cancel(generation uint64) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if generation != cb.generation {
+		return
+	}
+	cb.requests--
+	if cb.state == StateHalfOpen {
+		cb.probes--
+	}
+}
+
+// done reports the outcome of a request admitted by allow
+func (cb *CircuitBreaker) done(generation uint64, success bool) {
+	cb.mu.Lock()
+	defer cb.notify()
+	defer cb.mu.Unlock()
+	now := time.Now()
+	cb.tick(now)
+	if generation != cb.generation {
+		// the request started in a previous window or state
+		return
+	}
+	switch cb.state {
+	case StateClosed:
+		if !success {
+			cb.failures++
+			if cb.requests >= cb.settings.MinRequests &&
+				float64(cb.failures)/float64(cb.requests) >= cb.settings.FailureRatio {
+				cb.setState(StateOpen, now)
+			}
+		}
+	case StateHalfOpen:
+		if !success {
+			cb.setState(StateOpen, now)
+			return
+		}
+		cb.successes++
+		if cb.successes >= cb.settings.HalfOpenRequests {
+			cb.setState(StateClosed, now)
+		}
+	}
+}
+
+// tick moves an open circuit to half-open after the cool-down, and starts a new window when a closed one expires
+func (cb *CircuitBreaker) tick(now time.Time) {
+	switch cb.state {
+	case StateOpen:
+		if now.Sub(cb.openedAt) >= cb.settings.CoolDown {
+			cb.setState(StateHalfOpen, now)
+		}
+	case StateClosed:
+		if now.Sub(cb.windowStart) >= cb.settings.Window {
+			cb.reset(now)
+		}
+	}
+}
+
+// setState transitions the circuit and starts a new generation. cb.mu must be held
+func (cb *CircuitBreaker) setState(to CircuitState, now time.Time) {
+	from := cb.state
+	cb.state = to
+	cb.reset(now)
+	if to == StateOpen {
+		cb.openedAt = now
+	}
+	if cb.settings.OnStateChange != nil && from != to {
+		cb.pending = append(cb.pending, transition{from: from, to: to})
+	}
+}
+
+// notify passes pending transitions to OnStateChange. It must be called without cb.mu held
+func (cb *CircuitBreaker) notify() {
+	cb.mu.Lock()
+	pending := cb.pending
+	cb.pending = nil
+	cb.mu.Unlock()
+	for _, t := range pending {
+		cb.settings.OnStateChange(t.from, t.to)
+	}
+}
+
+func (cb *CircuitBreaker) reset(now time.Time) {
+	cb.generation++
+	cb.windowStart = now
+	cb.requests = 0
+	cb.failures = 0
+	cb.probes = 0
+	cb.successes = 0
+}
+
+// record reports the outcome of an attempt admitted by allow.
+// Transport errors and 5xx responses are failures, an attempt cut short by ctx is not counted.
+func (cb *CircuitBreaker) record(ctx context.Context, generation uint64, res *http.Response, err error) {
+	if err != nil && ctx.Err() != nil {
+		cb.cancel(generation)
+		return
+	}
+	cb.done(generation, err == nil && res.StatusCode < http.StatusInternalServerError)
+}
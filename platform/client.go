@@ -1,18 +1,20 @@
 package platform
 
+import "context"
+
 type Client interface {
 	// Ping does ping pong with the API server at /
 	Ping() bool
 	// AccountBalance returns a summary of the account's balance and available balance
 	AccountBalance() (AccountSummary, error)
 	// InitiateWithdrawal initiates a withdrawal from River Platform API by paying a specific invoice
-	InitiateWithdrawal(amount sats, invoice, currency, network string, fee_limit sats) (Withdrawal, error)
+	InitiateWithdrawal(amount Amount, invoice, currency, network string, fee_limit Amount) (Withdrawal, error)
 	// GetWithdrawal returns a withdrawal based on the passed withdrawal_id
 	GetWithdrawal(withdrawal_id string) (Withdrawal, error)
 	// CreateDepositInvoice creates an invoice to enable deposits to River Platform
-	CreateDepositInvoice(amount sats, label, network string) (DepositInvoice, error)
+	CreateDepositInvoice(amount Amount, label, network string) (DepositInvoice, error)
 	// GetDepositInvoices queries a list of invoices generated by River Platform
-	GetDepositInvoices(limit, next_timestamp int) ([]DepositInvoice, error)
+	GetDepositInvoices(limit, next_timestamp int) (DepositInvoiceList, error)
 	// GetDeposits returns a list of deposits (settled invoices) to River Platform
 	GetDeposits(limit, next_timestamp int) (DepositList, error)
 	// SubscribeToWebhook subscribes to a webhook
@@ -21,8 +23,39 @@ type Client interface {
 	GetSubscribedWebhook() (Webhook, error)
 	// DeleteWebhook deletes the existing webhook
 	DeleteWebhook() bool
+	// UnsubscribeWebhook deletes the existing webhook, returning why it failed
+	UnsubscribeWebhook() error
 	// DecodeInvoice decodes a Lightning Invoice using River Platform using `lncli decodepayreq`
 	DecodeInvoice(invoice string) (DecodedInvoice, error)
 	// EstimateLightningFee estimates Lightning Fee of an invoice using `lncli`
-	EstimateLightningFee(invoice string, amount sats) (FeeEstimate, error)
+	EstimateLightningFee(invoice string, amount Amount) (FeeEstimate, error)
+
+	// PingContext is Ping bound to ctx
+	PingContext(ctx context.Context, opts ...CallOption) bool
+	// AccountBalanceContext is AccountBalance bound to ctx
+	AccountBalanceContext(ctx context.Context, opts ...CallOption) (AccountSummary, error)
+	// InitiateWithdrawalContext is InitiateWithdrawal bound to ctx
+	InitiateWithdrawalContext(ctx context.Context, amount Amount, invoice, currency, network string, fee_limit Amount, opts ...CallOption) (Withdrawal, error)
+	// GetWithdrawalContext is GetWithdrawal bound to ctx
+	GetWithdrawalContext(ctx context.Context, withdrawal_id string, opts ...CallOption) (Withdrawal, error)
+	// CreateDepositInvoiceContext is CreateDepositInvoice bound to ctx
+	CreateDepositInvoiceContext(ctx context.Context, amount Amount, label, network string, opts ...CallOption) (DepositInvoice, error)
+	// GetDepositInvoicesContext is GetDepositInvoices bound to ctx
+	GetDepositInvoicesContext(ctx context.Context, limit, next_timestamp int, opts ...CallOption) (DepositInvoiceList, error)
+	// GetDepositsContext is GetDeposits bound to ctx
+	GetDepositsContext(ctx context.Context, limit, next_timestamp int, opts ...CallOption) (DepositList, error)
+	// SubscribeToWebhookContext is SubscribeToWebhook bound to ctx
+	SubscribeToWebhookContext(ctx context.Context, callback_url string, opts ...CallOption) (Webhook, error)
+	// GetSubscribedWebhookContext is GetSubscribedWebhook bound to ctx
+	GetSubscribedWebhookContext(ctx context.Context, opts ...CallOption) (Webhook, error)
+	// DeleteWebhookContext is DeleteWebhook bound to ctx
+	DeleteWebhookContext(ctx context.Context, opts ...CallOption) bool
+	// UnsubscribeWebhookContext is UnsubscribeWebhook bound to ctx
+	UnsubscribeWebhookContext(ctx context.Context, opts ...CallOption) error
+	// DecodeInvoiceContext is DecodeInvoice bound to ctx
+	DecodeInvoiceContext(ctx context.Context, invoice string, opts ...CallOption) (DecodedInvoice, error)
+	// EstimateLightningFeeContext is EstimateLightningFee bound to ctx
+	EstimateLightningFeeContext(ctx context.Context, invoice string, amount Amount, opts ...CallOption) (FeeEstimate, error)
 }
+
+var _ Client = (*PlatformClient)(nil)
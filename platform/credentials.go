@@ -0,0 +1,294 @@
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCredentialPollInterval is how often a FileCredentialProvider checks its file for changes
+// when no interval is given
+const DefaultCredentialPollInterval = 10 * time.Second
+
+// CredentialProvider supplies the API secret of an account, allowing it to be rotated while the client runs.
+// Implementations must be safe for concurrent use.
+type CredentialProvider interface {
+	// Credential returns the current API secret
+	Credential(ctx context.Context) (string, error)
+	// Refresh reloads the API secret from its source. It is called when Platform API rejects the current one
+	Refresh(ctx context.Context) error
+}
+
+// refresher is implemented by Authenticators whose credentials can be reloaded after Platform API rejects them
+type refresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// credentialAuth authenticates with the API secret of a CredentialProvider using the basic scheme
+type credentialAuth struct {
+	provider CredentialProvider
+}
+
+// CredentialAuth returns an Authenticator sending the secret of provider as BasicAuth does.
+// A call rejected with 401 Unauthorized is retried once after refreshing provider.
+func CredentialAuth(provider CredentialProvider) Authenticator {
+	return &credentialAuth{provider: provider}
+}
+
+func (ca *credentialAuth) Authenticate(ctx context.Context, req *http.Request) error {
+	secret, err := ca.provider.Credential(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("basic %s", createCredential(secret)))
+	return nil
+}
+
+func (ca *credentialAuth) Refresh(ctx context.Context) error {
+	return ca.provider.Refresh(ctx)
+}
+
+// WithCredentialProvider authenticates requests with the API secret of provider, read on every call.
+// The account id must still be set with WithAccountID.
+func WithCredentialProvider(provider CredentialProvider) Option {
+	return func(o *options) error {
+		if provider == nil {
+			return errors.New("platform: nil credential provider")
+		}
+		o.authenticator = CredentialAuth(provider)
+		return nil
+	}
+}
+
+// StaticCredentialProvider holds an API secret in memory that can be replaced with Rotate
+type StaticCredentialProvider struct {
+	mu     sync.RWMutex
+	secret string
+}
+
+// NewStaticCredentialProvider returns a StaticCredentialProvider holding secret
+func NewStaticCredentialProvider(secret string) (*StaticCredentialProvider, error) {
+	sp := &StaticCredentialProvider{}
+	if err := sp.Rotate(secret); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// Rotate replaces the secret. Calls authenticated afterwards use the new one
+func (sp *StaticCredentialProvider) Rotate(secret string) error {
+	if strings.TrimSpace(secret) == "" {
+		return errors.New("platform: empty api key")
+	}
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.secret = secret
+	return nil
+}
+
+func (sp *StaticCredentialProvider) Credential(context.Context) (string, error) {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	return sp.secret, nil
+}
+
+// Refresh does nothing, the secret only changes with Rotate
+func (sp *StaticCredentialProvider) Refresh(context.Context) error {
+	return nil
+}
+
+// EnvCredentialProvider reads the API secret from an environment variable on every call
+type EnvCredentialProvider struct {
+	name string
+}
+
+// NewEnvCredentialProvider returns an EnvCredentialProvider for the variable name, which must be set
+func NewEnvCredentialProvider(name string) (*EnvCredentialProvider, error) {
+	ep := &EnvCredentialProvider{name: name}
+	if _, err := ep.Credential(context.Background()); err != nil {
+		return nil, err
+	}
+	return ep, nil
+}
+
+func (ep *EnvCredentialProvider) Credential(context.Context) (string, error) {
+	secret := strings.TrimSpace(os.Getenv(ep.name))
+	if secret == "" {
+		return "", fmt.Errorf("platform: %s not set", ep.name)
+	}
+	return secret, nil
+}
+
+// Refresh does nothing, the variable is read on every call
+func (ep *EnvCredentialProvider) Refresh(context.Context) error {
+	return nil
+}
+
+// FileCredentialProvider reads the API secret from a file, such as a mounted Kubernetes secret,
+// and reloads it when the file's modification time or size changes
+type FileCredentialProvider struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	secret  string
+	modTime time.Time
+	size    int64
+	checked time.Time
+}
+
+// NewFileCredentialProvider reads the secret in path, checking it for changes at most once per interval.
+// An interval of 0 uses DefaultCredentialPollInterval.
+func NewFileCredentialProvider(path string, interval time.Duration) (*FileCredentialProvider, error) {
+	if interval < 0 {
+		return nil, fmt.Errorf("platform: poll interval must not be negative, got %s", interval)
+	}
+	if interval == 0 {
+		interval = DefaultCredentialPollInterval
+	}
+	fp := &FileCredentialProvider{path: path, interval: interval}
+	if err := fp.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return fp, nil
+}
+
+// Credential returns the secret, reloading the file if it changed since it was last checked.
+// The previous secret is kept while the file is missing or empty, as it may be in the middle of being replaced.
+func (fp *FileCredentialProvider) Credential(context.Context) (string, error) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	if time.Since(fp.checked) >= fp.interval {
+		fp.checked = time.Now()
+		if info, err := os.Stat(fp.path); err == nil && (!info.ModTime().Equal(fp.modTime) || info.Size() != fp.size) {
+			_ = fp.load()
+		}
+	}
+	return fp.secret, nil
+}
+
+// Refresh reloads the file whether or not it changed
+func (fp *FileCredentialProvider) Refresh(context.Context) error {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.checked = time.Now()
+	return fp.load()
+}
+
+// load reads the file. fp.mu must be held
+func (fp *FileCredentialProvider) load() error {
+	info, err := os.Stat(fp.path)
+	if err != nil {
+		return fmt.Errorf("platform: reading credential file: %w", err)
+	}
+	b, err := os.ReadFile(fp.path)
+	if err != nil {
+		return fmt.Errorf("platform: reading credential file: %w", err)
+	}
+	secret := strings.TrimSpace(string(b))
+	if secret == "" {
+		return fmt.Errorf("platform: credential file %s is empty", fp.path)
+	}
+	fp.secret = secret
+	fp.modTime = info.ModTime()
+	fp.size = info.Size()
+	return nil
+}
+
+// CommandCredentialProvider gets the API secret from an external command speaking the git credential helper protocol.
+// The command is given protocol, host and username attributes on stdin, one key=value per line,
+// and must print the secret as a password=<secret> line. The secret is cached until Refresh.
+type CommandCredentialProvider struct {
+	command string
+	args    []string
+	input   string
+
+	mu     sync.Mutex
+	secret string
+}
+
+// NewCommandCredentialProvider returns a CommandCredentialProvider asking command for the secret of
+// the account accountId of the Platform API at baseUrl. The command is not run until the first call.
+func NewCommandCredentialProvider(baseUrl, accountId, command string, args ...string) (*CommandCredentialProvider, error) {
+	base, err := parseBaseURL(baseUrl)
+	if err != nil {
+		return nil, err
+	}
+	u, _ := url.Parse(base)
+	if strings.TrimSpace(accountId) == "" {
+		return nil, errors.New("platform: empty account id")
+	}
+	if command == "" {
+		return nil, errors.New("platform: empty credential command")
+	}
+	var input strings.Builder
+	fmt.Fprintf(&input, "protocol=%s\nhost=%s\n", u.Scheme, u.Host)
+	if u.Path != "" {
+		fmt.Fprintf(&input, "path=%s\n", strings.TrimPrefix(u.Path, "/"))
+	}
+	fmt.Fprintf(&input, "username=%s\n\n", accountId)
+	return &CommandCredentialProvider{
+		command: command,
+		args:    args,
+		input:   input.String(),
+	}, nil
+}
+
+func (cp *CommandCredentialProvider) Credential(ctx context.Context) (string, error) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.secret == "" {
+		if err := cp.run(ctx); err != nil {
+			return "", err
+		}
+	}
+	return cp.secret, nil
+}
+
+// Refresh runs the command again
+func (cp *CommandCredentialProvider) Refresh(ctx context.Context) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.run(ctx)
+}
+
+// run runs the command and stores the secret it prints. cp.mu must be held
+func (cp *CommandCredentialProvider) run(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, cp.command, cp.args...)
+	cmd.Stdin = strings.NewReader(cp.input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("platform: credential command failed: %w: %s", err, msg)
+		}
+		return fmt.Errorf("platform: credential command failed: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := cutString(scanner.Text(), "=")
+		if ok && key == "password" && strings.TrimSpace(value) != "" {
+			cp.secret = strings.TrimSpace(value)
+			return nil
+		}
+	}
+	return errors.New("platform: credential command printed no password")
+}
+
+// cutString slices s around the first instance of sep, like strings.Cut
+func cutString(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,268 @@
+package platform
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultMaxResponseBytes is the largest response body read when WithMaxResponseBytes is not passed
+	DefaultMaxResponseBytes int64 = 10 << 20
+	// snippetLength is the number of body bytes quoted in a DecodeError
+	snippetLength = 256
+)
+
+// ErrResponseTooLarge is returned when a response body is larger than the client's limit
+var ErrResponseTooLarge = errors.New("platform: response body too large")
+
+// DecodeError is returned when a successful response cannot be decoded into its result
+type DecodeError struct {
+	Endpoint   Endpoint
+	StatusCode int
+	// Snippet is the start of the response body
+	Snippet string
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("platform: decoding %s response (status %d): %s; body: %q", e.Endpoint, e.StatusCode, e.Err.Error(), e.Snippet)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownFieldsError is the error of a DecodeError when strict decoding meets fields unknown to this package
+type UnknownFieldsError struct {
+	// Fields are the unknown fields, named as in SchemaDrift
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	quoted := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		quoted[i] = fmt.Sprintf("%q", field)
+	}
+	return "platform: unknown fields " + strings.Join(quoted, ", ")
+}
+
+// SchemaDrift reports a response field unknown to this package, a sign that Platform API changed
+type SchemaDrift struct {
+	Endpoint Endpoint
+	// Field is the name of the field, prefixed with the names of the objects containing it, such as "invoice.fee"
+	Field string
+}
+
+// WithMaxResponseBytes limits the size of the response bodies read by the client
+func WithMaxResponseBytes(n int64) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return fmt.Errorf("platform: max response bytes must be positive, got %d", n)
+		}
+		o.maxResponseBytes = n
+		return nil
+	}
+}
+
+// WithStrictDecoding makes calls fail with a DecodeError when a response has a field unknown to this package.
+// It is meant for staging environments, to notice changes of Platform API before they reach production.
+func WithStrictDecoding() Option {
+	return func(o *options) error {
+		o.strictDecoding = true
+		return nil
+	}
+}
+
+// WithSchemaDriftHandler calls fn when a response has a field unknown to this package.
+// Without WithStrictDecoding the call still succeeds.
+func WithSchemaDriftHandler(fn func(SchemaDrift)) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return errors.New("platform: nil schema drift handler")
+		}
+		o.onSchemaDrift = fn
+		return nil
+	}
+}
+
+// readBody reads at most limit bytes of body, failing with ErrResponseTooLarge if there is more
+func readBody(body io.Reader, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return b, err
+	}
+	if int64(len(b)) > limit {
+		return b[:limit], fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, limit)
+	}
+	return b, nil
+}
+
+// decodeBody decodes a successful response body into call.Result
+func (pc *PlatformClient) decodeBody(call *Call, body []byte) error {
+	call.body = body
+	if call.Result == nil {
+		return nil
+	}
+	statusCode := 0
+	if call.Response != nil {
+		statusCode = call.Response.StatusCode
+	}
+	decodeErr := func(err error) error {
+		return &DecodeError{
+			Endpoint:   call.Endpoint,
+			StatusCode: statusCode,
+			Snippet:    snippet(body),
+			Err:        err,
+		}
+	}
+
+	if err := json.Unmarshal(body, call.Result); err != nil {
+		return decodeErr(err)
+	}
+	if !pc.strictDecoding && pc.onSchemaDrift == nil {
+		return nil
+	}
+	fields := unknownFields(body, reflect.TypeOf(call.Result))
+	if len(fields) == 0 {
+		return nil
+	}
+	if pc.onSchemaDrift != nil {
+		for _, field := range fields {
+			pc.onSchemaDrift(SchemaDrift{Endpoint: call.Endpoint, Field: field})
+		}
+	}
+	if pc.strictDecoding {
+		return decodeErr(&UnknownFieldsError{Fields: fields})
+	}
+	for _, field := range fields {
+		pc.logger.Warnf("Unknown Field %q in %s Response", field, call.Endpoint)
+	}
+	return nil
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// unknownFields returns every field of body that a value of type t has no field for, in order and once each.
+// Values of types decoding themselves are not looked into
+func unknownFields(body []byte, t reflect.Type) []string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil
+	}
+	var fields []string
+	seen := make(map[string]bool)
+	var walk func(v interface{}, t reflect.Type, path string)
+	walk = func(v interface{}, t reflect.Type, path string) {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if reflect.PtrTo(t).Implements(jsonUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+			return
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return
+			}
+			known := jsonFields(t)
+			keys := make([]string, 0, len(obj))
+			for key := range obj {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				ft, ok := lookupField(known, key)
+				if !ok {
+					if !seen[path+key] {
+						seen[path+key] = true
+						fields = append(fields, path+key)
+					}
+					continue
+				}
+				walk(obj[key], ft, path+key+".")
+			}
+		case reflect.Slice, reflect.Array:
+			if arr, ok := v.([]interface{}); ok {
+				for _, elem := range arr {
+					walk(elem, t.Elem(), path)
+				}
+			}
+		case reflect.Map:
+			if obj, ok := v.(map[string]interface{}); ok {
+				for key, elem := range obj {
+					walk(elem, t.Elem(), path+key+".")
+				}
+			}
+		}
+	}
+	walk(v, t, "")
+	sort.Strings(fields)
+	return fields
+}
+
+// jsonFields returns the types of the fields of struct type t by their JSON names, as encoding/json sees them
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := cutString(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for embedded, et := range jsonFields(ft) {
+				if _, ok := fields[embedded]; !ok {
+					fields[embedded] = et
+				}
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// lookupField finds the field of key, preferring an exact match to a case insensitive one as encoding/json does
+func lookupField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if t, ok := fields[key]; ok {
+		return t, true
+	}
+	for name, t := range fields {
+		if strings.EqualFold(name, key) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// snippet returns the start of body for error messages
+func snippet(body []byte) string {
+	if len(body) <= snippetLength {
+		return string(body)
+	}
+	return string(body[:snippetLength]) + "..."
+}
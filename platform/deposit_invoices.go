@@ -2,11 +2,10 @@ package platform
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-
-	log "github.com/SachinMeier/platform-client-go/pkg/log"
 )
 
 type DepositInvoice struct {
@@ -14,6 +13,12 @@ type DepositInvoice struct {
 	Invoice   string `json:"destination"`
 	Network   string `json:"network"`
 	Timestamp int    `json:"timestamp"`
+	// IdempotencyKey is the key the invoice was created with. It is not part of the API response
+	IdempotencyKey string `json:"-"`
+	// Simulated is set on the invoices of a client created WithDryRun, which were never created and have no Invoice
+	Simulated bool `json:"-"`
+	// Quote is the fiat quote of an invoice created by CreateFiatDepositInvoice. It is not part of the API response
+	Quote *Quote `json:"-"`
 }
 
 type DepositInvoiceList struct {
@@ -32,8 +37,25 @@ func (dil *DepositInvoiceList) Count() int {
 }
 
 // CreateDepositInvoice creates an invoice to enable deposits to River Platform
-func (pc *PlatformClient) CreateDepositInvoice(amount sats, label, network string) (DepositInvoice, error) {
-	log.Info("Requesting Deposit Invoice")
+func (pc *PlatformClient) CreateDepositInvoice(amount Amount, label, network string) (DepositInvoice, error) {
+	return pc.CreateDepositInvoiceContext(pc.Context, amount, label, network)
+}
+
+// CreateDepositInvoiceContext is CreateDepositInvoice bound to ctx.
+// Unless WithIdempotencyKey is passed a key is generated. The key is set on the returned DepositInvoice
+// even when an error is returned, so that the call can be repeated without creating a second invoice.
+func (pc *PlatformClient) CreateDepositInvoiceContext(ctx context.Context, amount Amount, label, network string, opts ...CallOption) (DepositInvoice, error) {
+	pc.logger.Infof("Requesting Deposit Invoice")
+	key, opts, err := ensureIdempotencyKey(opts)
+	if err != nil {
+		pc.logger.Errorf("Idempotency Key Generation Failed: %s", err.Error())
+		return DepositInvoice{}, err
+	}
+	if pc.dryRun {
+		invoice, err := pc.simulateDepositInvoice(amount, network)
+		invoice.IdempotencyKey = key
+		return invoice, err
+	}
 
 	data := map[string]interface{}{
 		"amount":  amount,
@@ -41,32 +63,38 @@ func (pc *PlatformClient) CreateDepositInvoice(amount sats, label, network strin
 	}
 	body, err := json.Marshal(data)
 	if err != nil {
-		log.Errorf("JSON encoding error with amount: %d or network: %s", amount, network)
-		return DepositInvoice{}, err
+		pc.logger.Errorf("JSON encoding error with amount: %s or network: %s", amount, network)
+		return DepositInvoice{IdempotencyKey: key}, err
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/accounts/%s/deposit_intents", pc.BaseURL, pc.accountId), bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(pc.callContext(ctx), "POST", fmt.Sprintf("%s/accounts/%s/deposit_intents", pc.BaseURL, pc.accountId), bytes.NewBuffer(body))
 	if err != nil {
-		log.Error("Internal Error")
-		return DepositInvoice{}, err
+		pc.logger.Errorf("Internal Error")
+		return DepositInvoice{IdempotencyKey: key}, err
 	}
 
 	var invoice DepositInvoice
-	err = pc.sendRequest(req, &invoice)
+	err = pc.sendRequest(EndpointCreateDepositInvoice, req, &invoice, opts...)
 	if err != nil {
-		log.Errorf("Create Invoice Failed: %s", err.Error())
-		return DepositInvoice{}, err
+		pc.logger.Errorf("Create Invoice Failed: %s", err.Error())
+		return DepositInvoice{IdempotencyKey: key}, err
 	}
+	invoice.IdempotencyKey = key
 	return invoice, nil
 }
 
 // GetDepositInvoices queries a list of invoices generated by River Platform
 func (pc *PlatformClient) GetDepositInvoices(limit, next_timestamp int) (DepositInvoiceList, error) {
-	log.Info("Querying Deposit Invoices")
+	return pc.GetDepositInvoicesContext(pc.Context, limit, next_timestamp)
+}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/accounts/%s/deposit_intents", pc.BaseURL, pc.accountId), nil)
+// GetDepositInvoicesContext is GetDepositInvoices bound to ctx
+func (pc *PlatformClient) GetDepositInvoicesContext(ctx context.Context, limit, next_timestamp int, opts ...CallOption) (DepositInvoiceList, error) {
+	pc.logger.Infof("Querying Deposit Invoices")
+
+	req, err := http.NewRequestWithContext(pc.callContext(ctx), "GET", fmt.Sprintf("%s/accounts/%s/deposit_intents", pc.BaseURL, pc.accountId), nil)
 	if err != nil {
-		log.Errorf("Internal Error: %s", err.Error())
+		pc.logger.Errorf("Internal Error: %s", err.Error())
 		return DepositInvoiceList{}, err
 	}
 
@@ -79,9 +107,9 @@ func (pc *PlatformClient) GetDepositInvoices(limit, next_timestamp int) (Deposit
 	req.URL.RawQuery = query.Encode()
 
 	var invoices DepositInvoiceList
-	err = pc.sendRequest(req, &invoices)
+	err = pc.sendRequest(EndpointGetDepositInvoices, req, &invoices, opts...)
 	if err != nil {
-		log.Errorf("Create Invoice Failed: %s", err.Error())
+		pc.logger.Errorf("Create Invoice Failed: %s", err.Error())
 		return DepositInvoiceList{}, err
 	}
 	return invoices, nil
@@ -89,5 +117,10 @@ func (pc *PlatformClient) GetDepositInvoices(limit, next_timestamp int) (Deposit
 
 // GetNextPageDepositInvoices takes a DepositInvoiceList and returns the next limit DepositInvoices
 func (pc *PlatformClient) GetNextPageDepositInvoices(limit int, prev_list *DepositInvoiceList) (DepositInvoiceList, error) {
-	return pc.GetDepositInvoices(limit, prev_list.NextTimestamp)
+	return pc.GetNextPageDepositInvoicesContext(pc.Context, limit, prev_list)
+}
+
+// GetNextPageDepositInvoicesContext is GetNextPageDepositInvoices bound to ctx
+func (pc *PlatformClient) GetNextPageDepositInvoicesContext(ctx context.Context, limit int, prev_list *DepositInvoiceList, opts ...CallOption) (DepositInvoiceList, error) {
+	return pc.GetDepositInvoicesContext(ctx, limit, prev_list.NextTimestamp, opts...)
 }
@@ -1,16 +1,15 @@
 package platform
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-
-	log "github.com/SachinMeier/platform-client-go/pkg/log"
 )
 
 type Deposit struct {
 	Id        string         `json:"id"`
 	Invoice   DepositInvoice `json:"deposit_intent"`
-	Amount    sats           `json:"amount"`
+	Amount    Amount         `json:"amount"`
 	Detail    DepositDetail  `json:"deposit_details"`
 	State     string         `json:"state"`
 	Timestamp int            `json:"timestamp"`
@@ -32,10 +31,15 @@ func (dl *DepositList) Count() int {
 
 // GetDeposits returns a list of deposits (settled invoices) to River Platform
 func (pc *PlatformClient) GetDeposits(limit, next_timestamp int) (DepositList, error) {
-	log.Info("Querying Deposits")
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/accounts/%s/deposits", pc.BaseURL, pc.accountId), nil)
+	return pc.GetDepositsContext(pc.Context, limit, next_timestamp)
+}
+
+// GetDepositsContext is GetDeposits bound to ctx
+func (pc *PlatformClient) GetDepositsContext(ctx context.Context, limit, next_timestamp int, opts ...CallOption) (DepositList, error) {
+	pc.logger.Infof("Querying Deposits")
+	req, err := http.NewRequestWithContext(pc.callContext(ctx), "GET", fmt.Sprintf("%s/accounts/%s/deposits", pc.BaseURL, pc.accountId), nil)
 	if err != nil {
-		log.Error("Internal Error")
+		pc.logger.Errorf("Internal Error")
 		return DepositList{}, err
 	}
 
@@ -48,9 +52,9 @@ func (pc *PlatformClient) GetDeposits(limit, next_timestamp int) (DepositList, e
 	req.URL.RawQuery = query.Encode()
 
 	var deposits DepositList
-	err = pc.sendRequest(req, &deposits)
+	err = pc.sendRequest(EndpointGetDeposits, req, &deposits, opts...)
 	if err != nil {
-		log.Errorf("Deposits Query Failed: %s", err.Error())
+		pc.logger.Errorf("Deposits Query Failed: %s", err.Error())
 		return DepositList{}, err
 	}
 	return deposits, nil
@@ -0,0 +1,155 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRequest is wrapped by the errors of inputs rejected before a call is made
+var ErrInvalidRequest = errors.New("platform: invalid request")
+
+// ErrDryRun is returned by the mutating calls of a client created WithDryRun that have no result to simulate,
+// such as UnsubscribeWebhook
+var ErrDryRun = errors.New("platform: dry run, nothing was changed")
+
+// simulatedIdPrefix starts the ids of the results of dry run calls
+const simulatedIdPrefix = "simulated_"
+
+// WithDryRun makes InitiateWithdrawal, CreateDepositInvoice and SubscribeToWebhook validate their inputs
+// and return synthetic results with Simulated set, without calling Platform API. UnsubscribeWebhook returns
+// ErrDryRun and DeleteWebhook false.
+// Every other call still reaches Platform API, so that payout runs can be rehearsed against real balances.
+func WithDryRun() Option {
+	return func(o *options) error {
+		o.dryRun = true
+		return nil
+	}
+}
+
+// DryRun reports whether the client was created WithDryRun
+func (pc *PlatformClient) DryRun() bool {
+	return pc.dryRun
+}
+
+// invalid returns an error wrapping ErrInvalidRequest
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRequest, fmt.Sprintf(format, args...))
+}
+
+// validateNetwork checks that network is one supported by Platform API
+func validateNetwork(network string) error {
+	if network != LN {
+		return invalid("unsupported network %q", network)
+	}
+	return nil
+}
+
+// validateInvoice checks that invoice looks like a BOLT 11 Lightning invoice
+func validateInvoice(invoice string) error {
+	if !strings.HasPrefix(strings.ToLower(strings.TrimPrefix(invoice, "lightning:")), "ln") {
+		return invalid("%q is not a lightning invoice", invoice)
+	}
+	return nil
+}
+
+// validateWithdrawal checks the inputs of InitiateWithdrawal
+func validateWithdrawal(amount Amount, invoice, currency, network string, fee_limit Amount) error {
+	if amount.Sign() <= 0 {
+		return invalid("amount must be positive, got %s", amount)
+	}
+	if fee_limit.Sign() < 0 {
+		return invalid("fee limit must not be negative, got %s", fee_limit)
+	}
+	if !amount.WholeSats() || !fee_limit.WholeSats() {
+		return invalid("amounts must be whole sats, got %s and fee limit %s", amount, fee_limit)
+	}
+	if currency != BTC {
+		return invalid("unsupported currency %q", currency)
+	}
+	if err := validateNetwork(network); err != nil {
+		return err
+	}
+	return validateInvoice(invoice)
+}
+
+// validateWebhookURL checks the callback URL of SubscribeToWebhook
+func validateWebhookURL(callback_url string) error {
+	u, err := url.Parse(callback_url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return invalid("%q is not an http or https URL", callback_url)
+	}
+	return nil
+}
+
+// simulatedId returns a random id for the result of a dry run call
+func simulatedId() string {
+	id, err := NewIdempotencyKey()
+	if err != nil {
+		id = fmt.Sprint(time.Now().UnixNano())
+	}
+	return simulatedIdPrefix + id
+}
+
+// simulateWithdrawal returns the Withdrawal a dry run InitiateWithdrawal pretends to initiate
+func (pc *PlatformClient) simulateWithdrawal(amount Amount, invoice, currency, network string, fee_limit Amount) (Withdrawal, error) {
+	if err := validateWithdrawal(amount, invoice, currency, network, fee_limit); err != nil {
+		pc.logger.Errorf("Dry Run: Withdrawal Rejected: %s", err.Error())
+		return Withdrawal{}, err
+	}
+	pc.logger.Infof("Dry Run: Withdrawal of %s to %s Not Sent", amount, invoice)
+	return Withdrawal{
+		Amount:   amount,
+		Currency: currency,
+		Details: WithdrawalDetail{
+			Network:  network,
+			Invoice:  invoice,
+			FeeLimit: fee_limit,
+		},
+		State:     "pending",
+		Id:        simulatedId(),
+		Simulated: true,
+	}, nil
+}
+
+// simulateDepositInvoice returns the DepositInvoice a dry run CreateDepositInvoice pretends to create.
+// It has no invoice, as one that cannot be paid must not be handed out
+func (pc *PlatformClient) simulateDepositInvoice(amount Amount, network string) (DepositInvoice, error) {
+	if amount.Sign() < 0 {
+		err := invalid("amount must not be negative, got %s", amount)
+		pc.logger.Errorf("Dry Run: Deposit Invoice Rejected: %s", err.Error())
+		return DepositInvoice{}, err
+	}
+	if !amount.WholeSats() {
+		err := invalid("amount must be whole sats, got %s", amount)
+		pc.logger.Errorf("Dry Run: Deposit Invoice Rejected: %s", err.Error())
+		return DepositInvoice{}, err
+	}
+	if err := validateNetwork(network); err != nil {
+		pc.logger.Errorf("Dry Run: Deposit Invoice Rejected: %s", err.Error())
+		return DepositInvoice{}, err
+	}
+	pc.logger.Infof("Dry Run: Deposit Invoice of %s Not Created", amount)
+	return DepositInvoice{
+		Id:        simulatedId(),
+		Network:   network,
+		Timestamp: int(time.Now().UnixNano() / int64(time.Millisecond)),
+		Simulated: true,
+	}, nil
+}
+
+// simulateWebhook returns the Webhook a dry run SubscribeToWebhook pretends to subscribe
+func (pc *PlatformClient) simulateWebhook(callback_url string) (Webhook, error) {
+	if err := validateWebhookURL(callback_url); err != nil {
+		pc.logger.Errorf("Dry Run: Webhook Rejected: %s", err.Error())
+		return Webhook{}, err
+	}
+	pc.logger.Infof("Dry Run: Webhook %s Not Subscribed", callback_url)
+	return Webhook{
+		Url:       callback_url,
+		Enabled:   true,
+		Simulated: true,
+	}, nil
+}
@@ -0,0 +1,47 @@
+package platform
+
+// Endpoint names a Platform API operation. It is the name of the PlatformClient method that calls it
+type Endpoint string
+
+const (
+	EndpointPing                 Endpoint = "Ping"
+	EndpointAccountBalance       Endpoint = "AccountBalance"
+	EndpointInitiateWithdrawal   Endpoint = "InitiateWithdrawal"
+	EndpointGetWithdrawal        Endpoint = "GetWithdrawal"
+	EndpointCreateDepositInvoice Endpoint = "CreateDepositInvoice"
+	EndpointGetDepositInvoices   Endpoint = "GetDepositInvoices"
+	EndpointGetDeposits          Endpoint = "GetDeposits"
+	EndpointSubscribeToWebhook   Endpoint = "SubscribeToWebhook"
+	EndpointGetSubscribedWebhook Endpoint = "GetSubscribedWebhook"
+	EndpointDeleteWebhook        Endpoint = "DeleteWebhook"
+	EndpointDecodeInvoice        Endpoint = "DecodeInvoice"
+	EndpointEstimateLightningFee Endpoint = "EstimateLightningFee"
+)
+
+// EndpointGroup groups endpoints that share a rate limit
+type EndpointGroup string
+
+const (
+	// GroupReads holds the endpoints that do not change any state
+	GroupReads EndpointGroup = "reads"
+	// GroupWithdrawals holds InitiateWithdrawal
+	GroupWithdrawals EndpointGroup = "withdrawals"
+	// GroupWrites holds the other endpoints that change state
+	GroupWrites EndpointGroup = "writes"
+)
+
+// Group returns the EndpointGroup of e
+func (e Endpoint) Group() EndpointGroup {
+	switch e {
+	case EndpointInitiateWithdrawal:
+		return GroupWithdrawals
+	case EndpointCreateDepositInvoice, EndpointSubscribeToWebhook, EndpointDeleteWebhook:
+		return GroupWrites
+	}
+	return GroupReads
+}
+
+// Mutating reports whether calling e changes state on Platform API
+func (e Endpoint) Mutating() bool {
+	return e.Group() != GroupReads
+}
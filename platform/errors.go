@@ -0,0 +1,152 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors matched by APIError through errors.Is
+var (
+	// ErrUnauthorized is returned for 401 and 403 responses, usually a wrong account id or API secret
+	ErrUnauthorized = errors.New("platform: unauthorized")
+	// ErrNotFound is returned for 404 responses
+	ErrNotFound = errors.New("platform: not found")
+	// ErrRateLimited is returned for 429 responses
+	ErrRateLimited = errors.New("platform: rate limited")
+	// ErrInsufficientFunds is returned when the account cannot cover a withdrawal
+	ErrInsufficientFunds = errors.New("platform: insufficient funds")
+	// ErrInvoiceExpired is returned when a Lightning invoice has expired
+	ErrInvoiceExpired = errors.New("platform: invoice expired")
+)
+
+// error codes sent by Platform API that map to sentinel errors
+const (
+	codeInsufficientFunds = "insufficient_funds"
+	codeInvoiceExpired    = "invoice_expired"
+)
+
+// requestIDHeaders are the response headers that may carry the id River assigned to a request
+var requestIDHeaders = []string{"X-Request-Id", "Request-Id", "X-Amzn-Requestid"}
+
+// APIError is returned when Platform API responds with a non 2xx status
+type APIError struct {
+	StatusCode int
+	// Code and Message are parsed from a JSON error body, and empty otherwise
+	Code    string
+	Message string
+	// RequestID is the id River assigned to the request, if the response carried one
+	RequestID string
+	// Body is the raw response body
+	Body []byte
+}
+
+// errorBody covers the error body shapes returned by Platform API:
+// {"code": "...", "message": "..."}, {"error": "..."} and {"error": {"code": "...", "message": "..."}}
+type errorBody struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Detail  string          `json:"detail"`
+	Error   json.RawMessage `json:"error"`
+}
+
+// newAPIError builds an APIError from a failed response and its body
+func newAPIError(res *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: res.StatusCode,
+		RequestID:  requestID(res.Header),
+		Body:       body,
+	}
+
+	var eb errorBody
+	if json.Unmarshal(body, &eb) == nil {
+		apiErr.Code = eb.Code
+		apiErr.Message = eb.Message
+		if apiErr.Message == "" {
+			apiErr.Message = eb.Detail
+		}
+		if len(eb.Error) > 0 {
+			var nested errorBody
+			var msg string
+			if json.Unmarshal(eb.Error, &msg) == nil {
+				apiErr.Message = msg
+			} else if json.Unmarshal(eb.Error, &nested) == nil {
+				apiErr.Code = nested.Code
+				apiErr.Message = nested.Message
+			}
+		}
+	}
+	return apiErr
+}
+
+// Error keeps the "Error <status>: <body>" format of earlier versions, using the parsed message when there is one
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = string(e.Body)
+	}
+	if msg == "" {
+		msg = "[Response body is empty]"
+	}
+	if e.Code != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Code)
+	}
+	return fmt.Sprintf("Error %d: %s", e.StatusCode, msg)
+}
+
+// Is matches an APIError against the sentinel errors of this package
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrInsufficientFunds:
+		return strings.EqualFold(e.Code, codeInsufficientFunds)
+	case ErrInvoiceExpired:
+		return strings.EqualFold(e.Code, codeInvoiceExpired)
+	}
+	return false
+}
+
+// Temporary reports whether the failure is expected to go away when the request is repeated
+func (e *APIError) Temporary() bool {
+	return retryableStatus(e.StatusCode)
+}
+
+// IsRetryable reports whether repeating the call that returned err may succeed.
+// Transport errors and 408, 429, 502, 503 and 504 responses are retryable,
+// a canceled or expired Context is not.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryableStatus reports whether a response status is worth retrying
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
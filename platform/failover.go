@@ -0,0 +1,337 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FailoverSettings configures how a client with several base URLs tracks their health
+type FailoverSettings struct {
+	// ProbeInterval is how long an unhealthy base URL is left alone before it is pinged again
+	ProbeInterval time.Duration
+	// ProbeTimeout bounds each ping
+	ProbeTimeout time.Duration
+	// OnChange, if set, is called when the base URL used for reads or writes changes.
+	// It is called synchronously and must not block
+	OnChange func(EndpointChange)
+}
+
+// DefaultFailoverSettings returns the settings used when WithBaseURLs is passed without WithFailover
+func DefaultFailoverSettings() FailoverSettings {
+	return FailoverSettings{
+		ProbeInterval: 10 * time.Second,
+		ProbeTimeout:  5 * time.Second,
+	}
+}
+
+// EndpointChange reports that a client moved its reads or writes to another base URL
+type EndpointChange struct {
+	// Writes is set when the base URL of mutating calls changed, and unset for reads
+	Writes bool
+	From   string
+	To     string
+	// Err is the failure of From that caused the change, nil when a preferred base URL recovered
+	Err error
+}
+
+// EndpointStatus is the health of one base URL
+type EndpointStatus struct {
+	URL     string
+	Healthy bool
+	// LastError is the failure that made the base URL unhealthy
+	LastError error
+	// Since is when the base URL last became healthy or unhealthy
+	Since time.Time
+}
+
+// WithBaseURLs sets an ordered list of Platform API base URLs, the first being preferred.
+// Reads fail over to the next healthy base URL after a transport error or a 502, 503 or 504 response,
+// and move back once a preferred base URL answers a ping. Mutating calls stick to one base URL,
+// every attempt of a call included, and only move when that base URL fails.
+func WithBaseURLs(baseUrls ...string) Option {
+	return func(o *options) error {
+		if len(baseUrls) == 0 {
+			return errors.New("platform: no base URLs")
+		}
+		urls := make([]string, 0, len(baseUrls))
+		seen := make(map[string]bool, len(baseUrls))
+		for _, raw := range baseUrls {
+			u, err := parseBaseURL(raw)
+			if err != nil {
+				return err
+			}
+			if seen[u] {
+				return fmt.Errorf("platform: duplicate base URL %s", u)
+			}
+			seen[u] = true
+			urls = append(urls, u)
+		}
+		o.baseURL = urls[0]
+		o.baseURLs = urls
+		return nil
+	}
+}
+
+// WithFailover sets how the base URLs passed to WithBaseURLs are tracked
+func WithFailover(settings FailoverSettings) Option {
+	return func(o *options) error {
+		if settings.ProbeInterval <= 0 {
+			return fmt.Errorf("platform: probe interval must be positive, got %s", settings.ProbeInterval)
+		}
+		if settings.ProbeTimeout <= 0 {
+			return fmt.Errorf("platform: probe timeout must be positive, got %s", settings.ProbeTimeout)
+		}
+		o.failover = &settings
+		return nil
+	}
+}
+
+// endpointState is the health of one base URL
+type endpointState struct {
+	url       string
+	healthy   bool
+	lastErr   error
+	since     time.Time
+	nextProbe time.Time
+	probing   bool
+}
+
+// endpointPool tracks the health of the base URLs of a client and selects the ones calls are sent to
+type endpointPool struct {
+	settings FailoverSettings
+	// probe pings a base URL
+	probe func(ctx context.Context, baseUrl string) error
+
+	mu        sync.Mutex
+	endpoints []*endpointState
+	// read and write are the indexes of the base URLs of reads and mutating calls
+	read  int
+	write int
+}
+
+// newEndpointPool returns nil for fewer than two base URLs
+func newEndpointPool(urls []string, settings *FailoverSettings, probe func(context.Context, string) error) *endpointPool {
+	if len(urls) < 2 {
+		return nil
+	}
+	s := DefaultFailoverSettings()
+	if settings != nil {
+		s = *settings
+	}
+	ep := &endpointPool{settings: s, probe: probe}
+	now := time.Now()
+	for _, u := range urls {
+		ep.endpoints = append(ep.endpoints, &endpointState{url: u, healthy: true, since: now})
+	}
+	return ep
+}
+
+// readURL returns the base URL of the next read attempt
+func (ep *endpointPool) readURL() string {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.probeDue()
+	return ep.endpoints[ep.read].url
+}
+
+// writeURL returns the base URL of a mutating call, moving writes to the read base URL if theirs is unhealthy
+func (ep *endpointPool) writeURL() string {
+	ep.mu.Lock()
+	ep.probeDue()
+	var changes []EndpointChange
+	if w := ep.endpoints[ep.write]; !w.healthy && ep.endpoints[ep.read].healthy {
+		changes = append(changes, EndpointChange{Writes: true, From: w.url, To: ep.endpoints[ep.read].url, Err: w.lastErr})
+		ep.write = ep.read
+	}
+	u := ep.endpoints[ep.write].url
+	ep.mu.Unlock()
+	ep.notify(changes)
+	return u
+}
+
+// observe records the outcome of an attempt sent to baseUrl
+func (ep *endpointPool) observe(ctx context.Context, baseUrl string, res *http.Response, err error) {
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// the caller gave up, which says nothing of the endpoint
+	case err != nil:
+		ep.mark(baseUrl, err)
+	case endpointFailure(res.StatusCode):
+		ep.mark(baseUrl, fmt.Errorf("platform: %s responded %s", baseUrl, res.Status))
+	default:
+		ep.mark(baseUrl, nil)
+	}
+}
+
+// endpointFailure reports whether a status means the endpoint, rather than the call, failed
+func endpointFailure(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// mark records baseUrl as healthy if err is nil, or unhealthy, and selects the read base URL again
+func (ep *endpointPool) mark(baseUrl string, err error) {
+	ep.mu.Lock()
+	var changes []EndpointChange
+	for _, e := range ep.endpoints {
+		if e.url != baseUrl {
+			continue
+		}
+		now := time.Now()
+		if err == nil && !e.healthy {
+			e.healthy, e.lastErr, e.since = true, nil, now
+		} else if err != nil {
+			if e.healthy {
+				e.since = now
+			}
+			e.healthy, e.lastErr = false, err
+			e.nextProbe = now.Add(ep.settings.ProbeInterval)
+		}
+	}
+	if read := ep.preferred(); read != ep.read {
+		changes = append(changes, EndpointChange{From: ep.endpoints[ep.read].url, To: ep.endpoints[read].url, Err: ep.endpoints[ep.read].lastErr})
+		ep.read = read
+	}
+	ep.mu.Unlock()
+	ep.notify(changes)
+}
+
+// preferred returns the first healthy base URL, or the current one if none is. ep.mu must be held
+func (ep *endpointPool) preferred() int {
+	for i, e := range ep.endpoints {
+		if e.healthy {
+			return i
+		}
+	}
+	return ep.read
+}
+
+// probeDue pings the unhealthy base URLs whose probe is due, in the background. ep.mu must be held
+func (ep *endpointPool) probeDue() {
+	now := time.Now()
+	for _, e := range ep.endpoints {
+		if e.healthy || e.probing || now.Before(e.nextProbe) {
+			continue
+		}
+		e.probing = true
+		go func(e *endpointState) {
+			ctx, cancel := context.WithTimeout(context.Background(), ep.settings.ProbeTimeout)
+			defer cancel()
+			err := ep.probe(ctx, e.url)
+			ep.mu.Lock()
+			e.probing = false
+			ep.mu.Unlock()
+			ep.mark(e.url, err)
+		}(e)
+	}
+}
+
+// check pings every base URL and returns their status
+func (ep *endpointPool) check(ctx context.Context) []EndpointStatus {
+	var wg sync.WaitGroup
+	for _, u := range ep.urls() {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, ep.settings.ProbeTimeout)
+			defer cancel()
+			err := ep.probe(ctx, u)
+			if err != nil && ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				// canceled by the caller
+				return
+			}
+			ep.mark(u, err)
+		}(u)
+	}
+	wg.Wait()
+	return ep.status()
+}
+
+func (ep *endpointPool) urls() []string {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	urls := make([]string, 0, len(ep.endpoints))
+	for _, e := range ep.endpoints {
+		urls = append(urls, e.url)
+	}
+	return urls
+}
+
+func (ep *endpointPool) status() []EndpointStatus {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	status := make([]EndpointStatus, 0, len(ep.endpoints))
+	for _, e := range ep.endpoints {
+		status = append(status, EndpointStatus{URL: e.url, Healthy: e.healthy, LastError: e.lastErr, Since: e.since})
+	}
+	return status
+}
+
+// canFailOver reports whether a read that failed at baseUrl has a healthy base URL to move to
+func (ep *endpointPool) canFailOver(baseUrl string) bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	read := ep.endpoints[ep.read]
+	return read.healthy && read.url != baseUrl
+}
+
+func (ep *endpointPool) notify(changes []EndpointChange) {
+	if ep.settings.OnChange == nil {
+		return
+	}
+	for _, c := range changes {
+		ep.settings.OnChange(c)
+	}
+}
+
+// probe pings baseUrl directly, without the client's Middleware, retries or limits
+func (pc *PlatformClient) probe(ctx context.Context, baseUrl string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/", baseUrl), nil)
+	if err != nil {
+		return err
+	}
+	if err := pc.setHeaders(req); err != nil {
+		return err
+	}
+	res, err := pc.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, _ := readBody(res.Body, pc.maxResponseBytes)
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		return newAPIError(res, body)
+	}
+	return nil
+}
+
+// rebase returns a copy of u on baseUrl instead of the client's BaseURL
+func (pc *PlatformClient) rebase(u *url.URL, baseUrl string) (*url.URL, error) {
+	s := u.String()
+	if baseUrl == "" || baseUrl == pc.BaseURL || !strings.HasPrefix(s, pc.BaseURL) {
+		return u, nil
+	}
+	return url.Parse(baseUrl + strings.TrimPrefix(s, pc.BaseURL))
+}
+
+// BaseURLs returns the health of the client's base URLs in order of preference, or nil if it has only BaseURL
+func (pc *PlatformClient) BaseURLs() []EndpointStatus {
+	if pc.endpoints == nil {
+		return nil
+	}
+	return pc.endpoints.status()
+}
+
+// CheckBaseURLs pings every base URL of the client, updating their health, and returns it.
+// It returns nil if the client has only BaseURL
+func (pc *PlatformClient) CheckBaseURLs(ctx context.Context) []EndpointStatus {
+	if pc.endpoints == nil {
+		return nil
+	}
+	return pc.endpoints.check(pc.callContext(ctx))
+}
@@ -0,0 +1,263 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// RoundingMode is how a fiat value is rounded to the minor unit of its currency
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds to the nearest minor unit, ties to the even one (banker's rounding)
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds to the nearest minor unit, ties away from zero
+	RoundHalfUp
+	// RoundDown rounds toward zero
+	RoundDown
+	// RoundUp rounds away from zero
+	RoundUp
+)
+
+func (m RoundingMode) String() string {
+	switch m {
+	case RoundHalfEven:
+		return "half-even"
+	case RoundHalfUp:
+		return "half-up"
+	case RoundDown:
+		return "down"
+	case RoundUp:
+		return "up"
+	}
+	return fmt.Sprintf("RoundingMode(%d)", int(m))
+}
+
+// currencyDecimals are the ISO 4217 minor units of the currencies without two decimals
+var currencyDecimals = map[string]int{
+	"BHD": 3, "CLP": 0, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3,
+	"LYD": 3, "OMR": 3, "PYG": 0, "TND": 3, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+}
+
+// CurrencyDecimals returns the number of decimals of the minor unit of an ISO 4217 currency, 2 for most
+func CurrencyDecimals(currency string) int {
+	if d, ok := currencyDecimals[strings.ToUpper(currency)]; ok {
+		return d
+	}
+	return 2
+}
+
+// FiatAmount is an amount of fiat currency in its minor unit, such as cents
+type FiatAmount struct {
+	Currency string `json:"currency"`
+	// Minor is the amount in the minor unit of Currency
+	Minor int64 `json:"minor"`
+	// Decimals is the number of decimals of the minor unit
+	Decimals int `json:"decimals"`
+	// Rate is the rate the amount was converted at, zero for an amount derived from others or parsed
+	Rate Rate `json:"rate"`
+}
+
+// String formats the amount with its decimals, such as "1234.50 USD"
+func (f FiatAmount) String() string {
+	return fmt.Sprintf("%s %s", formatMinor(f.Minor, f.Decimals), f.Currency)
+}
+
+// formatMinor formats n minor units with decimals, keeping trailing zeros
+func formatMinor(n int64, decimals int) string {
+	s := big.NewInt(n).String()
+	if decimals <= 0 {
+		return s
+	}
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	return sign + s[:len(s)-decimals] + "." + s[len(s)-decimals:]
+}
+
+// ParseFiatAmount parses a decimal price such as "19.99" in currency, which must be exact to its minor unit
+func ParseFiatAmount(price, currency string) (FiatAmount, error) {
+	code, err := currencyCode(currency)
+	if err != nil {
+		return FiatAmount{}, err
+	}
+	price = strings.TrimSpace(price)
+	value, ok := new(big.Rat).SetString(price)
+	if !ok || strings.Contains(price, "/") {
+		return FiatAmount{}, fmt.Errorf("platform: invalid %s amount %q", code, price)
+	}
+	decimals := CurrencyDecimals(code)
+	value.Mul(value, new(big.Rat).SetInt(pow10(decimals)))
+	if !value.IsInt() || !value.Num().IsInt64() {
+		return FiatAmount{}, fmt.Errorf("platform: invalid %s amount %q: more precise than %d decimals", code, price, decimals)
+	}
+	return FiatAmount{Currency: code, Minor: value.Num().Int64(), Decimals: decimals}, nil
+}
+
+// pow10 returns 10^n
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// ToFiat converts amount to the currency of rate, rounding to its minor unit with mode
+func ToFiat(amount Amount, rate Rate, mode RoundingMode) (FiatAmount, error) {
+	if rate.perBTC == nil {
+		return FiatAmount{}, errors.New("platform: rate not created by ParseRate")
+	}
+	decimals := CurrencyDecimals(rate.Currency)
+	// minor units = msat * perBTC * 10^decimals / MsatPerBTC, computed exactly before rounding
+	value := new(big.Rat).SetInt64(amount.Msats())
+	value.Mul(value, rate.perBTC)
+	value.Mul(value, new(big.Rat).SetInt(pow10(decimals)))
+	value.Quo(value, new(big.Rat).SetInt64(MsatPerBTC))
+	minor, err := round(value, mode)
+	if err != nil {
+		return FiatAmount{}, err
+	}
+	return FiatAmount{Currency: rate.Currency, Minor: minor, Decimals: decimals, Rate: rate}, nil
+}
+
+// FromFiat converts a fiat amount to whole sats at rate, rounding with mode.
+// The amount must have the decimals of its currency, as ParseFiatAmount gives
+func FromFiat(fiat FiatAmount, rate Rate, mode RoundingMode) (Amount, error) {
+	if rate.perBTC == nil {
+		return Amount{}, errors.New("platform: rate not created by ParseRate")
+	}
+	if fiat.Currency != rate.Currency {
+		return Amount{}, fmt.Errorf("platform: cannot convert %s at a %s rate", fiat.Currency, rate.Currency)
+	}
+	if err := checkDecimals(fiat); err != nil {
+		return Amount{}, err
+	}
+	// sats = minor * SatsPerBTC / (perBTC * 10^decimals)
+	value := new(big.Rat).SetInt64(fiat.Minor)
+	value.Mul(value, new(big.Rat).SetInt64(SatsPerBTC))
+	value.Quo(value, new(big.Rat).Mul(rate.perBTC, new(big.Rat).SetInt(pow10(fiat.Decimals))))
+	sats, err := round(value, mode)
+	if err != nil {
+		return Amount{}, err
+	}
+	if sats > math.MaxInt64/MsatPerSat || sats < math.MinInt64/MsatPerSat {
+		return Amount{}, ErrAmountOverflow
+	}
+	return Sats(sats), nil
+}
+
+// checkDecimals checks that a fiat amount has the decimals of its currency, so that Minor is in its minor unit
+func checkDecimals(fiat FiatAmount) error {
+	if d := CurrencyDecimals(fiat.Currency); fiat.Decimals != d {
+		return invalid("%s amount has %d decimals, the currency has %d", fiat.Currency, fiat.Decimals, d)
+	}
+	return nil
+}
+
+// round rounds value to an integer with mode
+func round(value *big.Rat, mode RoundingMode) (int64, error) {
+	num, den := value.Num(), value.Denom()
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() != 0 {
+		// twice the remainder against the denominator tells below, at or above half
+		half := new(big.Int).Abs(r)
+		half.Lsh(half, 1)
+		cmp := half.Cmp(den)
+		away := false
+		switch mode {
+		case RoundHalfEven:
+			away = cmp > 0 || (cmp == 0 && q.Bit(0) == 1)
+		case RoundHalfUp:
+			away = cmp >= 0
+		case RoundDown:
+		case RoundUp:
+			away = true
+		default:
+			return 0, fmt.Errorf("platform: unknown rounding mode %s", mode)
+		}
+		if away {
+			q.Add(q, big.NewInt(int64(num.Sign())))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, errors.New("platform: fiat amount overflows")
+	}
+	return q.Int64(), nil
+}
+
+// FiatConverter values amounts in a fiat currency with the rates of a RateProvider
+type FiatConverter struct {
+	Provider RateProvider
+	// Currency is the ISO 4217 code amounts are converted to
+	Currency string
+	// Rounding is applied to every amount converted, RoundHalfEven by default
+	Rounding RoundingMode
+	// QuoteLock is how long a Quote holds its rate, DefaultQuoteLock if zero
+	QuoteLock time.Duration
+	// QuoteTolerance is how many minor units a payment may differ from a quoted price and still be exact
+	QuoteTolerance int64
+}
+
+// FiatAccountSummary is an AccountSummary valued in fiat
+type FiatAccountSummary struct {
+	Id               string
+	Balance          FiatAmount
+	AvailableBalance FiatAmount
+	// ReservedBalance is Balance - AvailableBalance after rounding, so that the three add up
+	ReservedBalance FiatAmount
+}
+
+// Convert values amount at the rate of time at
+func (fc *FiatConverter) Convert(ctx context.Context, amount Amount, at time.Time) (FiatAmount, error) {
+	if fc.Provider == nil {
+		return FiatAmount{}, errors.New("platform: nil rate provider")
+	}
+	rate, err := fc.Provider.Rate(ctx, fc.Currency, at)
+	if err != nil {
+		return FiatAmount{}, err
+	}
+	return ToFiat(amount, rate, fc.Rounding)
+}
+
+// AccountSummary values the balances of as at the rate of time at, with a single rate
+func (fc *FiatConverter) AccountSummary(ctx context.Context, as AccountSummary, at time.Time) (FiatAccountSummary, error) {
+	balance, err := fc.Convert(ctx, as.Balance, at)
+	if err != nil {
+		return FiatAccountSummary{}, err
+	}
+	available, err := ToFiat(as.AvailableBalance, balance.Rate, fc.Rounding)
+	if err != nil {
+		return FiatAccountSummary{}, err
+	}
+	reserved, ok := subInt64(balance.Minor, available.Minor)
+	if !ok {
+		return FiatAccountSummary{}, errors.New("platform: fiat amount overflows")
+	}
+	return FiatAccountSummary{
+		Id:               as.Id,
+		Balance:          balance,
+		AvailableBalance: available,
+		ReservedBalance:  FiatAmount{Currency: balance.Currency, Minor: reserved, Decimals: balance.Decimals},
+	}, nil
+}
+
+// Deposit values a deposit at the rate of its timestamp
+func (fc *FiatConverter) Deposit(ctx context.Context, d Deposit) (FiatAmount, error) {
+	return fc.Convert(ctx, d.Amount, timestampTime(d.Timestamp))
+}
+
+// Withdrawal values a withdrawal at the rate of time at. Withdrawals carry no timestamp of their own
+func (fc *FiatConverter) Withdrawal(ctx context.Context, w Withdrawal, at time.Time) (FiatAmount, error) {
+	return fc.Convert(ctx, w.Amount, at)
+}
+
+// timestampTime converts a Platform API timestamp, in milliseconds since the Unix epoch, to a time
+func timestampTime(ms int) time.Time {
+	return time.Unix(0, int64(ms)*int64(time.Millisecond)).UTC()
+}
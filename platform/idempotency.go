@@ -0,0 +1,38 @@
+package platform
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// WithIdempotencyKey sends key in the IdempotencyKeyHeader of a call.
+// Repeating a mutating call with the same key lets Platform API apply it at most once,
+// and makes the call eligible for retries under a RetryPolicy.
+func WithIdempotencyKey(key string) CallOption {
+	return func(co *callOptions) {
+		co.idempotencyKey = key
+	}
+}
+
+// NewIdempotencyKey returns a random (version 4) UUID suitable for WithIdempotencyKey
+func NewIdempotencyKey() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// ensureIdempotencyKey returns the key passed in opts, generating one and appending it to a copy of opts if none was passed
+func ensureIdempotencyKey(opts []CallOption) (string, []CallOption, error) {
+	if key := newCallOptions(opts).idempotencyKey; key != "" {
+		return key, opts, nil
+	}
+	key, err := NewIdempotencyKey()
+	if err != nil {
+		return "", opts, err
+	}
+	return key, append(opts[:len(opts):len(opts)], WithIdempotencyKey(key)), nil
+}
@@ -0,0 +1,234 @@
+package platform
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency histogram used when NewMetrics is given none
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// status labels of calls that did not get a response
+const (
+	statusError    = "error"
+	statusCanceled = "canceled"
+)
+
+// Metrics collects per endpoint metrics of the calls made by one or more clients
+// and serves them in the Prometheus text exposition format
+type Metrics struct {
+	buckets []float64
+
+	mu       sync.Mutex
+	requests map[requestKey]uint64
+	latency  map[Endpoint]*histogram
+	retries  map[Endpoint]uint64
+	inFlight map[Endpoint]int64
+}
+
+type requestKey struct {
+	endpoint Endpoint
+	status   string
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewMetrics returns an empty Metrics with the given latency buckets, in seconds, or DefaultLatencyBuckets
+func NewMetrics(buckets ...float64) (*Metrics, error) {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	for i := range buckets {
+		if i > 0 && buckets[i] <= buckets[i-1] {
+			return nil, errors.New("platform: latency buckets must be increasing")
+		}
+	}
+	return &Metrics{
+		buckets:  buckets,
+		requests: make(map[requestKey]uint64),
+		latency:  make(map[Endpoint]*histogram),
+		retries:  make(map[Endpoint]uint64),
+		inFlight: make(map[Endpoint]int64),
+	}, nil
+}
+
+// WithMetrics records the calls of the client in m. The metrics Middleware runs before any other
+func WithMetrics(m *Metrics) Option {
+	return func(o *options) error {
+		if m == nil {
+			return errors.New("platform: nil metrics")
+		}
+		o.metrics = m
+		return nil
+	}
+}
+
+// middleware records every call passing through it
+func (m *Metrics) middleware(next Handler) Handler {
+	return func(ctx context.Context, call *Call) error {
+		m.mu.Lock()
+		m.inFlight[call.Endpoint]++
+		m.mu.Unlock()
+
+		start := time.Now()
+		err := next(ctx, call)
+		m.observe(ctx, call, time.Since(start), err)
+		return err
+	}
+}
+
+func (m *Metrics) observe(ctx context.Context, call *Call, elapsed time.Duration, err error) {
+	status := statusError
+	switch {
+	case call.Response != nil:
+		status = strconv.Itoa(call.Response.StatusCode)
+	case ctx.Err() != nil:
+		status = statusCanceled
+	case err == nil:
+		// a Middleware answered the call without a response
+		status = strconv.Itoa(http.StatusOK)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight[call.Endpoint]--
+	m.requests[requestKey{endpoint: call.Endpoint, status: status}]++
+	if call.Attempts > 1 {
+		m.retries[call.Endpoint] += uint64(call.Attempts - 1)
+	}
+	h, ok := m.latency[call.Endpoint]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.latency[call.Endpoint] = h
+	}
+	seconds := elapsed.Seconds()
+	for i, le := range m.buckets {
+		if seconds <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := m.WriteTo(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	m.mu.Lock()
+	requests := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		requests = append(requests, key)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].endpoint != requests[j].endpoint {
+			return requests[i].endpoint < requests[j].endpoint
+		}
+		return requests[i].status < requests[j].status
+	})
+
+	cw.printf("# HELP platform_client_requests_total Calls to Platform API by endpoint and response status.\n")
+	cw.printf("# TYPE platform_client_requests_total counter\n")
+	for _, key := range requests {
+		cw.printf("platform_client_requests_total{endpoint=%s,status=%s} %d\n",
+			quoteLabel(string(key.endpoint)), quoteLabel(key.status), m.requests[key])
+	}
+
+	cw.printf("# HELP platform_client_request_duration_seconds Latency of calls to Platform API, including retries.\n")
+	cw.printf("# TYPE platform_client_request_duration_seconds histogram\n")
+	for _, endpoint := range sortedEndpoints(m.latency) {
+		h := m.latency[endpoint]
+		label := quoteLabel(string(endpoint))
+		for i, le := range m.buckets {
+			cw.printf("platform_client_request_duration_seconds_bucket{endpoint=%s,le=%q} %d\n",
+				label, strconv.FormatFloat(le, 'g', -1, 64), h.counts[i])
+		}
+		cw.printf("platform_client_request_duration_seconds_bucket{endpoint=%s,le=\"+Inf\"} %d\n", label, h.count)
+		cw.printf("platform_client_request_duration_seconds_sum{endpoint=%s} %s\n", label, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		cw.printf("platform_client_request_duration_seconds_count{endpoint=%s} %d\n", label, h.count)
+	}
+
+	cw.printf("# HELP platform_client_retries_total Attempts repeated by the retry policy.\n")
+	cw.printf("# TYPE platform_client_retries_total counter\n")
+	for _, endpoint := range sortedEndpoints(m.retries) {
+		cw.printf("platform_client_retries_total{endpoint=%s} %d\n", quoteLabel(string(endpoint)), m.retries[endpoint])
+	}
+
+	cw.printf("# HELP platform_client_in_flight_requests Calls to Platform API in progress.\n")
+	cw.printf("# TYPE platform_client_in_flight_requests gauge\n")
+	for _, endpoint := range sortedEndpoints(m.inFlight) {
+		cw.printf("platform_client_in_flight_requests{endpoint=%s} %d\n", quoteLabel(string(endpoint)), m.inFlight[endpoint])
+	}
+	m.mu.Unlock()
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// sortedEndpoints returns the keys of a map keyed by Endpoint in order
+func sortedEndpoints(m interface{}) []Endpoint {
+	var endpoints []Endpoint
+	switch m := m.(type) {
+	case map[Endpoint]*histogram:
+		for e := range m {
+			endpoints = append(endpoints, e)
+		}
+	case map[Endpoint]uint64:
+		for e := range m {
+			endpoints = append(endpoints, e)
+		}
+	case map[Endpoint]int64:
+		for e := range m {
+			endpoints = append(endpoints, e)
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i] < endpoints[j] })
+	return endpoints
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// quoteLabel quotes a label value as required by the exposition format
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+// countingWriter counts the bytes written and keeps the first error
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) printf(format string, args ...interface{}) {
+	if cw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(cw.w, format, args...)
+	cw.n += int64(n)
+	cw.err = err
+}
@@ -0,0 +1,106 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// Call describes one call to Platform API made by a PlatformClient method
+type Call struct {
+	// Endpoint is the operation being called
+	Endpoint Endpoint
+	// AccountID is the River account the call is made for
+	AccountID string
+	// Request is the request of the call with every header set. Each attempt sends a copy of it
+	Request *http.Request
+	// Response is the response of the last attempt once the call has completed. Its body has been consumed
+	Response *http.Response
+	// Result points to the value the response is decoded into, and is nil for calls without a result.
+	// It is populated once the Handler returns without error
+	Result interface{}
+	// Attempts is the number of attempts made so far
+	Attempts int
+
+	// body is the successful response body Result was decoded from
+	body []byte
+	// auth and proxy are those of the account's client, which may differ from the client running the Handler
+	auth  Authenticator
+	proxy *url.URL
+}
+
+// Handler performs a Call
+type Handler func(ctx context.Context, call *Call) error
+
+// Middleware wraps the Handler that performs every call of a client.
+// It may inspect or replace call.Request before calling next, and read call.Response and call.Result after.
+type Middleware func(next Handler) Handler
+
+// BeforeRequestFunc is called before every attempt with the request about to be sent.
+// Returning an error aborts the call with that error, without retrying it.
+type BeforeRequestFunc func(ctx context.Context, call *Call, req *http.Request) error
+
+// AfterResponseFunc is called after every attempt with its response or transport error.
+// It must not read or close the response body.
+type AfterResponseFunc func(ctx context.Context, call *Call, res *http.Response, err error)
+
+// WithMiddleware wraps every call of the client in mw. Middleware run in the order they are passed,
+// the first one being the outermost, and after any Middleware passed in earlier options.
+// Middleware see a call once, however many attempts it takes.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(o *options) error {
+		for _, m := range mw {
+			if m == nil {
+				return errors.New("platform: nil middleware")
+			}
+		}
+		o.middleware = append(o.middleware, mw...)
+		return nil
+	}
+}
+
+// WithBeforeRequest adds a hook called before every attempt, after every header is set.
+// Hooks run in the order they are added.
+func WithBeforeRequest(fn BeforeRequestFunc) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return errors.New("platform: nil before request hook")
+		}
+		o.beforeRequest = append(o.beforeRequest, fn)
+		return nil
+	}
+}
+
+// WithAfterResponse adds a hook called after every attempt, before its response is decoded or retried.
+// Hooks run in the order they are added.
+func WithAfterResponse(fn AfterResponseFunc) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return errors.New("platform: nil after response hook")
+		}
+		o.afterResponse = append(o.afterResponse, fn)
+		return nil
+	}
+}
+
+// chain wraps h in mw, the first Middleware being the outermost
+func chain(mw []Middleware, h Handler) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// hookError marks an error returned by a BeforeRequestFunc so that it is not retried
+type hookError struct {
+	err error
+}
+
+func (he *hookError) Error() string {
+	return he.err.Error()
+}
+
+func (he *hookError) Unwrap() error {
+	return he.err
+}
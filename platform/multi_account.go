@@ -0,0 +1,169 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownAccount is returned by MultiClient.ForAccount for an account it does not hold
+var ErrUnknownAccount = errors.New("platform: unknown account")
+
+// Account holds the credentials of one River account
+type Account struct {
+	ID     string
+	APIKey string
+	// Authenticator authenticates the account instead of APIKey when set
+	Authenticator Authenticator
+	// Proxy is the URL of a proxy used for the account's requests instead of the client's, as for WithProxy
+	Proxy string
+}
+
+// option returns the Option setting the account and its credentials
+func (a Account) option() Option {
+	if a.Authenticator != nil {
+		return func(o *options) error {
+			if err := WithAccountID(a.ID)(o); err != nil {
+				return err
+			}
+			return WithAuthenticator(a.Authenticator)(o)
+		}
+	}
+	return WithAccount(a.ID, a.APIKey)
+}
+
+// authenticator returns the Authenticator of the account
+func (a Account) authenticator() Authenticator {
+	if a.Authenticator != nil {
+		return a.Authenticator
+	}
+	return BasicAuth(a.APIKey)
+}
+
+// MultiClient holds several River accounts and hands out a PlatformClient per account.
+// The clients share one http.Client, rate limiter, circuit breaker, cache and metrics.
+// Accounts with their own Proxy share the connection pool too, each proxy having its own connections.
+type MultiClient struct {
+	clients map[string]*PlatformClient
+	ids     []string
+}
+
+// NewMultiClient creates a MultiClient for accounts. opts configure every account's client
+// and must not include WithAccount or WithAuthenticator
+func NewMultiClient(accounts []Account, opts ...Option) (*MultiClient, error) {
+	if len(accounts) == 0 {
+		return nil, errors.New("platform: no accounts")
+	}
+	proxies := make([]*url.URL, len(accounts))
+	opts = append(opts[:len(opts):len(opts)], accounts[0].option())
+	for i, account := range accounts {
+		if account.Proxy == "" {
+			continue
+		}
+		u, err := parseProxyURL(account.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w (account %s)", err, account.ID)
+		}
+		proxies[i] = u
+	}
+	for _, proxy := range proxies {
+		if proxy != nil {
+			opts = append(opts, withAccountProxies())
+			break
+		}
+	}
+	base, err := NewPlatformClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	mc := &MultiClient{
+		clients: make(map[string]*PlatformClient, len(accounts)),
+	}
+	for i, account := range accounts {
+		if _, ok := mc.clients[account.ID]; ok {
+			return nil, fmt.Errorf("platform: duplicate account %s", account.ID)
+		}
+		// validate the credentials as NewPlatformClient would
+		if err := account.option()(&options{}); err != nil {
+			return nil, err
+		}
+		mc.clients[account.ID] = base.forAccount(account.ID, account.authenticator(), proxies[i])
+		mc.ids = append(mc.ids, account.ID)
+	}
+	return mc, nil
+}
+
+// forAccount returns a copy of pc for another account, sharing every other setting.
+// A nil proxy keeps the proxy of pc
+func (pc *PlatformClient) forAccount(accountId string, auth Authenticator, proxy *url.URL) *PlatformClient {
+	c := *pc
+	c.accountId = accountId
+	c.auth = auth
+	if proxy != nil {
+		c.proxy = proxy
+	}
+	return &c
+}
+
+// ForAccount returns the client of the account id
+func (mc *MultiClient) ForAccount(id string) (*PlatformClient, error) {
+	pc, ok := mc.clients[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, id)
+	}
+	return pc, nil
+}
+
+// Accounts returns the ids of the accounts held, in the order they were given
+func (mc *MultiClient) Accounts() []string {
+	return append([]string(nil), mc.ids...)
+}
+
+// AccountErrors collects the errors of a call made for several accounts, by account id
+type AccountErrors map[string]error
+
+func (ae AccountErrors) Error() string {
+	ids := make([]string, 0, len(ae))
+	for id := range ae {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	msgs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", id, ae[id].Error()))
+	}
+	return "platform: " + strings.Join(msgs, "; ")
+}
+
+// AccountBalances queries the balance of every account concurrently.
+// It returns the balances that succeeded, and an AccountErrors for the ones that failed.
+func (mc *MultiClient) AccountBalances(ctx context.Context, opts ...CallOption) (map[string]AccountSummary, error) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	balances := make(map[string]AccountSummary, len(mc.ids))
+	errs := make(AccountErrors)
+	for _, id := range mc.ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			summary, err := mc.clients[id].AccountBalanceContext(ctx, opts...)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[id] = err
+				return
+			}
+			balances[id] = summary
+		}(id)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return balances, errs
+	}
+	return balances, nil
+}
@@ -0,0 +1,196 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/SachinMeier/platform-client-go/pkg/log"
+)
+
+const (
+	// DefaultTimeout is the HTTP timeout used when neither WithHTTPClient nor WithTimeout is passed
+	DefaultTimeout = time.Minute
+	// DefaultUserAgent is the User-Agent header sent when WithUserAgent is not passed
+	DefaultUserAgent = "platform-client-go"
+)
+
+// Option configures a PlatformClient created by NewPlatformClient
+type Option func(*options) error
+
+// options collects the settings passed to NewPlatformClient before the client is built
+type options struct {
+	ctx        context.Context
+	baseURL    string
+	accountId  string
+	apiKey     string
+	httpClient *http.Client
+	timeout    time.Duration
+	userAgent  string
+	logger     log.Logger
+
+	authenticator Authenticator
+	tls           tlsOptions
+	dryRun        bool
+	baseURLs      []string
+	failover      *FailoverSettings
+	wireDump      *wireDump
+	proxy         *url.URL
+	// accountProxies is set by NewMultiClient when an account has its own proxy
+	accountProxies bool
+
+	retryPolicy     *RetryPolicy
+	rateLimit       *RateLimit
+	groupRateLimits map[EndpointGroup]RateLimit
+	circuitBreaker  *CircuitBreaker
+	metrics         *Metrics
+	auditLog        *AuditLog
+	tracer          Tracer
+
+	maxResponseBytes int64
+	strictDecoding   bool
+	onSchemaDrift    func(SchemaDrift)
+
+	cacheTTL         time.Duration
+	cachedEndpoints  map[Endpoint]bool
+	coalesceRequests bool
+
+	middleware    []Middleware
+	beforeRequest []BeforeRequestFunc
+	afterResponse []AfterResponseFunc
+}
+
+func defaultOptions() options {
+	return options{
+		ctx:              context.Background(),
+		userAgent:        DefaultUserAgent,
+		logger:           log.Std,
+		maxResponseBytes: DefaultMaxResponseBytes,
+	}
+}
+
+// validate checks that every setting required to talk to Platform API is present
+func (o *options) validate() error {
+	if o.baseURL == "" {
+		return errors.New("platform: base URL is required, use WithBaseURL")
+	}
+	if o.accountId == "" {
+		return errors.New("platform: account id is required, use WithAccount")
+	}
+	if o.apiKey == "" && o.authenticator == nil {
+		return errors.New("platform: api key is required, use WithAccount or WithAuthenticator")
+	}
+	return nil
+}
+
+// WithContext sets the Context used by calls that are not given their own
+func WithContext(ctx context.Context) Option {
+	return func(o *options) error {
+		if ctx == nil {
+			return errors.New("platform: nil context")
+		}
+		o.ctx = ctx
+		return nil
+	}
+}
+
+// WithBaseURL sets the root URL of Platform API. A URL without a scheme is assumed to be https
+func WithBaseURL(baseUrl string) Option {
+	return func(o *options) error {
+		u, err := parseBaseURL(baseUrl)
+		if err != nil {
+			return err
+		}
+		o.baseURL = u
+		o.baseURLs = nil
+		return nil
+	}
+}
+
+// WithAccount sets the River account and the API secret used to authenticate it
+func WithAccount(accountId, apiKey string) Option {
+	return func(o *options) error {
+		if strings.TrimSpace(accountId) == "" {
+			return errors.New("platform: empty account id")
+		}
+		if strings.TrimSpace(apiKey) == "" {
+			return errors.New("platform: empty api key")
+		}
+		o.accountId = accountId
+		o.apiKey = apiKey
+		return nil
+	}
+}
+
+// WithHTTPClient sets the http.Client used to send requests
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) error {
+		if client == nil {
+			return errors.New("platform: nil http client")
+		}
+		o.httpClient = client
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of the http.Client, including one passed with WithHTTPClient
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) error {
+		if timeout <= 0 {
+			return fmt.Errorf("platform: timeout must be positive, got %s", timeout)
+		}
+		o.timeout = timeout
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) error {
+		if strings.TrimSpace(userAgent) == "" {
+			return errors.New("platform: empty user agent")
+		}
+		o.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithLogger sets the Logger used by the client. Pass log.Nop to silence it
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) error {
+		if logger == nil {
+			return errors.New("platform: nil logger")
+		}
+		o.logger = logger
+		return nil
+	}
+}
+
+// parseBaseURL validates a base URL and normalizes it without a trailing slash
+func parseBaseURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("platform: empty base URL")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("platform: invalid base URL %q: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("platform: base URL %q must use http or https", raw)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("platform: base URL %q has no host", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("platform: base URL %q must not have a query or fragment", raw)
+	}
+	return strings.TrimRight(u.String(), "/"), nil
+}
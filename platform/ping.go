@@ -1,26 +1,30 @@
 package platform
 
 import (
+	"context"
 	"fmt"
 	"net/http"
-
-	log "github.com/SachinMeier/platform-client-go/pkg/log"
 )
 
 // Ping does ping pong with the API server at /
 func (pc *PlatformClient) Ping() bool {
-	log.Info("Ping Server")
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/", pc.BaseURL), nil)
+	return pc.PingContext(pc.Context)
+}
+
+// PingContext is Ping bound to ctx
+func (pc *PlatformClient) PingContext(ctx context.Context, opts ...CallOption) bool {
+	pc.logger.Infof("Ping Server")
+	req, err := http.NewRequestWithContext(pc.callContext(ctx), "GET", fmt.Sprintf("%s/", pc.BaseURL), nil)
 	if err != nil {
-		log.Error("Internal Error")
+		pc.logger.Errorf("Internal Error")
 		return false
 	}
 	// empty body response
-	err = pc.sendRequest(req, nil)
+	err = pc.sendRequest(EndpointPing, req, nil, opts...)
 	if err != nil {
-		log.Errorf("Ping Failed: %s", err.Error())
+		pc.logger.Errorf("Ping Failed: %s", err.Error())
 		return false
 	}
-	log.Info("Ping Succeeded")
+	pc.logger.Infof("Ping Succeeded")
 	return true
 }
@@ -2,109 +2,379 @@ package platform
 
 import (
 	"context"
-	b64 "encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"time"
 
 	log "github.com/SachinMeier/platform-client-go/pkg/log"
 )
 
-type sats int
-
 type PlatformClient struct {
-	BaseURL    string
-	credential string
-	accountId  string
-	HTTPClient *http.Client
-	Context    context.Context
+	BaseURL   string
+	auth      Authenticator
+	accountId string
+	userAgent string
+	logger    log.Logger
+	retry     *RetryPolicy
+	limiter   *rateLimiter
+	breaker   *CircuitBreaker
+	// proxy is the proxy of the account's requests, set for MultiClient accounts with their own proxy
+	proxy *url.URL
+	// dryRun is set by WithDryRun
+	dryRun bool
+	// endpoints tracks the base URLs passed to WithBaseURLs, and is nil for a single BaseURL
+	endpoints *endpointPool
+	// handler is invoke wrapped in the client's Middleware
+	handler       Handler
+	beforeRequest []BeforeRequestFunc
+	afterResponse []AfterResponseFunc
+
+	maxResponseBytes int64
+	strictDecoding   bool
+	onSchemaDrift    func(SchemaDrift)
+	wireDump         *wireDump
+	HTTPClient       *http.Client
+	Context          context.Context
 }
 
-// setHeaders sets the headers for all HTTP requests
-func setHeaders(req *http.Request, credential string) {
+// setHeaders sets the headers for all HTTP requests, authenticating them with the client's Authenticator
+func (pc *PlatformClient) setHeaders(req *http.Request) error {
 	req.Header.Set("Content-Type", "application/json; charset-utf-8")
 	req.Header.Set("Accept", "application/json; charset-utf-8")
-	req.Header.Set("Authorization", fmt.Sprintf("basic %s", credential))
+	req.Header.Set("User-Agent", pc.userAgent)
+	return pc.auth.Authenticate(req.Context(), req)
 }
 
-// handleResponse handles HTTP responses and unmarshals JSON to the appropriate object
-func handleResponse(res *http.Response, response interface{}) error {
+// handleResponse handles HTTP responses and unmarshals JSON to call.Result.
+// Responses with a non 2xx status are returned as an *APIError
+func (pc *PlatformClient) handleResponse(call *Call, res *http.Response) error {
+	body, readErr := readBody(res.Body, pc.maxResponseBytes)
 	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
-		// var errresp ErrorResponse
-		var errmsg string
-		body, err := io.ReadAll(res.Body)
-		log.Errorf(string(body))
-		// err is not json
-		if err != nil {
-			// body is empty
-			if len(body) == 0 {
-				errmsg = "[Response body is empty]"
-			}
-		} else {
-			errmsg = string(body)
+		if readErr != nil {
+			pc.logger.Warnf("Reading Error Response Failed: %s", readErr.Error())
 		}
-		errmsg = fmt.Sprintf("Error %d: %s", res.StatusCode, errmsg)
-		log.Error(errmsg)
-		return errors.New(errmsg)
+		apiErr := newAPIError(res, body)
+		pc.logger.Errorf("%s", apiErr.Error())
+		return apiErr
+	}
+	if readErr != nil {
+		pc.logger.Errorf("Reading %s Response Failed: %s", call.Endpoint, readErr.Error())
+		return readErr
 	}
 
-	if response != nil {
-		err := json.NewDecoder(res.Body).Decode(response)
-		if err != nil {
-			msg, err := io.ReadAll(res.Body)
-			log.Errorf("%s", string(msg))
-			return err
-		}
+	if err := pc.decodeBody(call, body); err != nil {
+		pc.logger.Errorf("%s", err.Error())
+		return err
 	}
 	return nil
 }
 
-// createCredential creates the basic auth credential used to authenticate requests to Platform API
-func createCredential(apiKey string) string {
-	key := fmt.Sprintf("%s:%s", apiKey, apiKey)
-	return b64.StdEncoding.EncodeToString([]byte(key))
+// sendRequest handles sending HTTP requests. The request's Context bounds the call.
+// The call passes through the client's Middleware before it is sent by invoke
+func (pc *PlatformClient) sendRequest(endpoint Endpoint, req *http.Request, response interface{}, opts ...CallOption) error {
+	co := newCallOptions(opts)
+	start := time.Now()
+	ctx := req.Context()
+	if co.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, co.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+	for key, values := range co.header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if co.idempotencyKey != "" {
+		req.Header.Set(IdempotencyKeyHeader, co.idempotencyKey)
+	}
+	if err := pc.setHeaders(req); err != nil {
+		pc.logger.Errorf("Authenticating %s Failed: %s", endpoint, err.Error())
+		return err
+	}
+
+	call := &Call{
+		Endpoint:  endpoint,
+		AccountID: pc.accountId,
+		Request:   req,
+		Result:    response,
+		auth:      pc.auth,
+		proxy:     pc.proxy,
+	}
+	err := pc.handler(ctx, call)
+	if co.meta != nil {
+		*co.meta = newResponseMeta(call, time.Since(start), err)
+	}
+	return err
 }
 
-// sendRequest handles sending HTTP requests
-func (pc *PlatformClient) sendRequest(req *http.Request, response interface{}) error {
-	req = req.WithContext(pc.Context)
-	setHeaders(req, pc.credential)
+// invoke is the innermost Handler. It sends call.Request, retrying it according to the client's RetryPolicy,
+// and decodes the response into call.Result
+func (pc *PlatformClient) invoke(ctx context.Context, call *Call) error {
+	req := call.Request
+	maxAttempts := 1
+	if pc.retry != nil && canRetry(req) {
+		maxAttempts = pc.retry.MaxAttempts
+	}
+	reauthenticated := false
+	// every attempt of a mutating call goes to the same base URL
+	var writeURL string
+	if pc.endpoints != nil && call.Endpoint.Mutating() {
+		writeURL = pc.endpoints.writeURL()
+	}
+	failovers := 0
+	for attempt := 1; ; attempt++ {
+		call.Attempts = attempt
+		if pc.limiter != nil {
+			if err := pc.limiter.wait(ctx, call.Endpoint); err != nil {
+				return err
+			}
+		}
+		var generation uint64
+		if pc.breaker != nil {
+			g, err := pc.breaker.allow()
+			if err != nil {
+				pc.logger.Warnf("%s %s Rejected: %s", req.Method, req.URL.Path, err.Error())
+				return err
+			}
+			generation = g
+		}
+		baseUrl := writeURL
+		if pc.endpoints != nil && baseUrl == "" {
+			baseUrl = pc.endpoints.readURL()
+		}
+		res, err := pc.doAttempt(ctx, call, baseUrl)
+		var hookErr *hookError
+		if errors.As(err, &hookErr) {
+			// the request was never sent
+			if pc.breaker != nil {
+				pc.breaker.cancel(generation)
+			}
+			return hookErr.err
+		}
+		if pc.limiter != nil {
+			pc.limiter.observe(call.Endpoint, res)
+		}
+		if pc.breaker != nil {
+			pc.breaker.record(ctx, generation, res, err)
+		}
+		if pc.endpoints != nil {
+			pc.endpoints.observe(ctx, baseUrl, res, err)
+		}
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		retrying := attempt < maxAttempts && shouldRetry(res, err)
+		var delay time.Duration
+		reauthenticating, failingOver := false, false
+		if retrying {
+			delay = pc.retry.delay(attempt, res)
+		} else if writeURL == "" && pc.endpoints != nil && failovers < len(pc.endpoints.endpoints)-1 &&
+			(err != nil || endpointFailure(res.StatusCode)) && pc.endpoints.canFailOver(baseUrl) {
+			// a read is sent again at once to the next healthy base URL
+			failovers++
+			failingOver = true
+			retrying = true
+		} else if !reauthenticated && res != nil && res.StatusCode == http.StatusUnauthorized {
+			// the credentials may have been rotated, retry once with fresh ones
+			reauthenticated = true
+			reauthenticating = pc.reauthenticate(ctx, call)
+			retrying = reauthenticating
+		}
+		if pc.retry != nil && pc.retry.OnAttempt != nil {
+			a := Attempt{
+				Number:   attempt,
+				Method:   req.Method,
+				URL:      attemptURL(pc, req, baseUrl),
+				Err:      err,
+				Retrying: retrying,
+				Delay:    delay,
+			}
+			if res != nil {
+				a.StatusCode = res.StatusCode
+			}
+			pc.retry.OnAttempt(a)
+		}
 
-	res, err := pc.HTTPClient.Do(req)
-	if err != nil {
+		if !retrying {
+			if err != nil {
+				return err
+			}
+			defer res.Body.Close()
+			call.Response = res
+			return pc.handleResponse(call, res)
+		}
+
+		if res != nil {
+			// drain the body so that the connection can be reused
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		if reauthenticating {
+			pc.logger.Warnf("Retrying %s %s with Refreshed Credentials", req.Method, req.URL.Path)
+		} else if failingOver {
+			pc.logger.Warnf("Failing Over %s %s from %s", req.Method, req.URL.Path, baseUrl)
+		} else {
+			pc.logger.Warnf("Retrying %s %s in %s (attempt %d of %d)", req.Method, req.URL.Path, delay, attempt+1, maxAttempts)
+		}
+		timer := time.NewTimer(delay)
 		select {
-		case <-pc.Context.Done():
-			// log.Errorf("Context Expired: %s", pc.Context.Err().Error())
-			return pc.Context.Err()
-		default:
-			// log.Error(err.Error())
-			return err
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
 		}
 	}
-	defer res.Body.Close()
-	// log.Infof("%s %s %d", req.Method, req.URL, res.StatusCode)
-	return handleResponse(res, response)
 }
 
-// NewPlatformClient creates a new PlatformClient
-func NewPlatformClient(ctx context.Context, baseUrl, accountId, apiKey string) *PlatformClient {
-	if ctx == context.TODO() {
-		ctx = context.Background()
+// reauthenticate refreshes the credentials of the call's Authenticator, if it supports it,
+// and authenticates call.Request again. It reports whether the call should be retried
+func (pc *PlatformClient) reauthenticate(ctx context.Context, call *Call) bool {
+	r, ok := call.auth.(refresher)
+	if !ok {
+		return false
+	}
+	if err := r.Refresh(ctx); err != nil {
+		pc.logger.Errorf("Refreshing Credentials Failed: %s", err.Error())
+		return false
+	}
+	if err := call.auth.Authenticate(ctx, call.Request); err != nil {
+		pc.logger.Errorf("Authenticating %s Failed: %s", call.Endpoint, err.Error())
+		return false
 	}
+	return true
+}
 
-	return &PlatformClient{
-		BaseURL:    baseUrl,
-		accountId:  accountId,
-		credential: createCredential(apiKey),
-		HTTPClient: &http.Client{
-			Timeout: time.Minute,
-		},
-		Context: ctx,
+// attemptURL returns the URL an attempt of req was sent to
+func attemptURL(pc *PlatformClient, req *http.Request, baseUrl string) string {
+	u, err := pc.rebase(req.URL, baseUrl)
+	if err != nil {
+		return req.URL.String()
+	}
+	return u.String()
+}
+
+// doAttempt sends a copy of call.Request once to baseUrl, or the client's BaseURL if empty,
+// with a fresh copy of its body, running the client's BeforeRequest and AfterResponse hooks around it
+func (pc *PlatformClient) doAttempt(ctx context.Context, call *Call, baseUrl string) (*http.Response, error) {
+	if call.proxy != nil {
+		ctx = context.WithValue(ctx, proxyKey{}, call.proxy)
+	}
+	req := call.Request.Clone(ctx)
+	u, err := pc.rebase(req.URL, baseUrl)
+	if err != nil {
+		return nil, err
+	}
+	if u != req.URL {
+		req.URL = u
+		req.Host = ""
+	}
+	if call.Request.GetBody != nil {
+		body, err := call.Request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+	for _, before := range pc.beforeRequest {
+		if err := before(ctx, call, req); err != nil {
+			return nil, &hookError{err: err}
+		}
+	}
+	var reqDump []byte
+	started := time.Now()
+	if pc.wireDump != nil {
+		reqDump = pc.wireDump.dumpRequest(req)
+	}
+	res, err := pc.HTTPClient.Do(req)
+	if pc.wireDump != nil {
+		pc.wireDump.write(call, started, reqDump, res, err, pc.maxResponseBytes)
+	}
+	for _, after := range pc.afterResponse {
+		after(ctx, call, res, err)
+	}
+	return res, err
+}
+
+// NewPlatformClient creates a new PlatformClient configured by opts.
+// WithBaseURL and WithAccount are required, every other Option has a default.
+// A client authenticated by WithAuthenticator may set its account with WithAccountID instead.
+func NewPlatformClient(opts ...Option) (*PlatformClient, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{
+		Timeout: DefaultTimeout,
+	}
+	if o.httpClient != nil {
+		// copy so that WithTimeout does not modify the caller's client
+		c := *o.httpClient
+		httpClient = &c
+	}
+	if o.timeout != 0 {
+		httpClient.Timeout = o.timeout
+	}
+	transport, err := newTransport(&o, httpClient.Transport)
+	if err != nil {
+		return nil, err
+	}
+	if transport != nil {
+		httpClient.Transport = transport
+	}
+
+	auth := o.authenticator
+	if auth == nil {
+		auth = BasicAuth(o.apiKey)
+	}
+
+	pc := &PlatformClient{
+		BaseURL:    o.baseURL,
+		accountId:  o.accountId,
+		auth:       auth,
+		userAgent:  o.userAgent,
+		logger:     o.logger,
+		retry:      o.retryPolicy,
+		limiter:    newRateLimiter(o.rateLimit, o.groupRateLimits),
+		breaker:    o.circuitBreaker,
+		HTTPClient: httpClient,
+		Context:    o.ctx,
+		dryRun:     o.dryRun,
+
+		beforeRequest: o.beforeRequest,
+		afterResponse: o.afterResponse,
+
+		maxResponseBytes: o.maxResponseBytes,
+		strictDecoding:   o.strictDecoding,
+		onSchemaDrift:    o.onSchemaDrift,
+		wireDump:         o.wireDump,
+	}
+	middleware := []Middleware{tracingMiddleware(o.tracer)}
+	if cache := newReadCache(o.cacheTTL, o.cachedEndpoints, o.coalesceRequests); cache != nil {
+		middleware = append(middleware, cache.middleware(pc))
+	}
+	middleware = append(middleware, o.middleware...)
+	if o.auditLog != nil {
+		middleware = append([]Middleware{o.auditLog.middleware(pc)}, middleware...)
+	}
+	if o.metrics != nil {
+		middleware = append([]Middleware{o.metrics.middleware}, middleware...)
 	}
+	pc.endpoints = newEndpointPool(o.baseURLs, o.failover, pc.probe)
+	pc.handler = chain(middleware, pc.invoke)
+	return pc, nil
 }
 
 // LoadEnv reads the necessary variables for creating a PlatformClient from environment and returns them
@@ -131,12 +401,14 @@ func LoadEnv() (string, string, string, error) {
 	return baseUrl, accountId, apiKey, nil
 }
 
-// NewPlatformClientFromEnv combines LoadEnv and NewPlatformClient to create a client directly from env variables
-func NewPlatformClientFromEnv() *PlatformClient {
+// NewPlatformClientFromEnv combines LoadEnv and NewPlatformClient to create a client directly from env variables.
+// opts are applied after the settings read from the environment.
+func NewPlatformClientFromEnv(opts ...Option) (*PlatformClient, error) {
 	baseUrl, accountId, apiKey, err := LoadEnv()
 	if err != nil {
 		log.Errorf("Failed to Load Environment Variables: %s", err.Error())
-		return nil
+		return nil, err
 	}
-	return NewPlatformClient(context.TODO(), apiKey, accountId, baseUrl)
+	opts = append([]Option{WithBaseURL(baseUrl), WithAccount(accountId, apiKey)}, opts...)
+	return NewPlatformClient(opts...)
 }
@@ -0,0 +1,213 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultQuoteLock is how long a Quote holds its rate when the FiatConverter sets no QuoteLock
+const DefaultQuoteLock = 15 * time.Minute
+
+// quoteIdPrefix starts the id of every Quote
+const quoteIdPrefix = "quote_"
+
+// Quote is a fiat price converted to sats at a rate locked until ExpiresAt
+type Quote struct {
+	Id string `json:"id"`
+	// Price is the fiat price quoted
+	Price FiatAmount `json:"price"`
+	// Amount is Price in sats at Rate
+	Amount Amount `json:"amount"`
+	Rate   Rate   `json:"rate"`
+	// Tolerance is how many minor units of Price a payment may differ by and still be exact
+	Tolerance int64     `json:"tolerance"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// InvoiceId is the id of the DepositInvoice created for the quote, if any
+	InvoiceId string `json:"invoice_id,omitempty"`
+}
+
+// Expired reports whether the rate of the quote is no longer locked at t
+func (q *Quote) Expired(t time.Time) bool {
+	return !t.Before(q.ExpiresAt)
+}
+
+// Quote converts price, which must be in fc.Currency, to sats at the current rate, locking the rate for fc.QuoteLock
+func (fc *FiatConverter) Quote(ctx context.Context, price FiatAmount) (Quote, error) {
+	if fc == nil || fc.Provider == nil {
+		return Quote{}, errors.New("platform: nil rate provider")
+	}
+	currency, err := currencyCode(fc.Currency)
+	if err != nil {
+		return Quote{}, err
+	}
+	if price.Currency != currency {
+		return Quote{}, invalid("cannot quote a %s price in %s", price.Currency, currency)
+	}
+	if err := checkDecimals(price); err != nil {
+		return Quote{}, err
+	}
+	if price.Minor <= 0 {
+		return Quote{}, invalid("price must be positive, got %s", price)
+	}
+	lock := fc.QuoteLock
+	if lock < 0 {
+		return Quote{}, fmt.Errorf("platform: quote lock must not be negative, got %s", lock)
+	}
+	if lock == 0 {
+		lock = DefaultQuoteLock
+	}
+	now := time.Now()
+	rate, err := fc.Provider.Rate(ctx, price.Currency, now)
+	if err != nil {
+		return Quote{}, err
+	}
+	amount, err := FromFiat(price, rate, fc.Rounding)
+	if err != nil {
+		return Quote{}, err
+	}
+	if amount.Sign() <= 0 {
+		return Quote{}, invalid("%s is less than a sat at %s", price, rate)
+	}
+	id, err := NewIdempotencyKey()
+	if err != nil {
+		return Quote{}, err
+	}
+	price.Rate = Rate{}
+	return Quote{
+		Id:        quoteIdPrefix + id,
+		Price:     price,
+		Amount:    amount,
+		Rate:      rate,
+		Tolerance: fc.QuoteTolerance,
+		CreatedAt: now,
+		ExpiresAt: now.Add(lock),
+	}, nil
+}
+
+// PaymentStatus is how a payment compares to a quoted price
+type PaymentStatus int
+
+const (
+	// PaymentExact is a payment of the quoted amount, or within the quote's tolerance of its price
+	PaymentExact PaymentStatus = iota
+	// PaymentUnderpaid is a payment worth less than the quoted price
+	PaymentUnderpaid
+	// PaymentOverpaid is a payment worth more than the quoted price
+	PaymentOverpaid
+)
+
+func (s PaymentStatus) String() string {
+	switch s {
+	case PaymentExact:
+		return "exact"
+	case PaymentUnderpaid:
+		return "underpaid"
+	case PaymentOverpaid:
+		return "overpaid"
+	}
+	return fmt.Sprintf("PaymentStatus(%d)", int(s))
+}
+
+// PaymentJudgement compares a payment to a Quote
+type PaymentJudgement struct {
+	Status PaymentStatus
+	// Paid is the amount received
+	Paid Amount
+	// PaidFiat is Paid valued at the quote's rate
+	PaidFiat FiatAmount
+	// Difference is PaidFiat less the quoted price, negative when underpaid
+	Difference FiatAmount
+	// Late is set when the payment was received after the quote expired, and its rate may no longer be fair
+	Late bool
+}
+
+// Judge compares paid, received at time at, to the price of the quote, valued at the locked rate
+func (q *Quote) Judge(paid Amount, at time.Time) (PaymentJudgement, error) {
+	if err := checkDecimals(q.Price); err != nil {
+		return PaymentJudgement{}, err
+	}
+	paidFiat, err := ToFiat(paid, q.Rate, RoundHalfEven)
+	if err != nil {
+		return PaymentJudgement{}, err
+	}
+	diff, ok := subInt64(paidFiat.Minor, q.Price.Minor)
+	if !ok {
+		return PaymentJudgement{}, errors.New("platform: fiat amount overflows")
+	}
+	j := PaymentJudgement{
+		Paid:       paid,
+		PaidFiat:   paidFiat,
+		Difference: FiatAmount{Currency: q.Price.Currency, Minor: diff, Decimals: q.Price.Decimals},
+		Late:       q.Expired(at),
+	}
+	switch {
+	case paid == q.Amount, diff >= -q.Tolerance && diff <= q.Tolerance:
+		j.Status = PaymentExact
+	case diff < 0:
+		j.Status = PaymentUnderpaid
+	default:
+		j.Status = PaymentOverpaid
+	}
+	return j, nil
+}
+
+// JudgeDeposits compares the sum of deposits to the price of the quote. If the quote has an InvoiceId,
+// deposits to other invoices are ignored. The payment is late if any deposit arrived after the quote expired
+func (q *Quote) JudgeDeposits(deposits ...Deposit) (PaymentJudgement, error) {
+	var paid Amount
+	var last time.Time
+	for _, d := range deposits {
+		if q.InvoiceId != "" && d.Invoice.Id != q.InvoiceId {
+			continue
+		}
+		var err error
+		if paid, err = paid.Add(d.Amount); err != nil {
+			return PaymentJudgement{}, err
+		}
+		if at := timestampTime(d.Timestamp); at.After(last) {
+			last = at
+		}
+	}
+	return q.Judge(paid, last)
+}
+
+// CreateFiatDepositInvoice creates a deposit invoice for a fiat price, see CreateFiatDepositInvoiceContext
+func (pc *PlatformClient) CreateFiatDepositInvoice(fc *FiatConverter, price FiatAmount, label, network string) (DepositInvoice, error) {
+	return pc.CreateFiatDepositInvoiceContext(pc.Context, fc, price, label, network)
+}
+
+// CreateFiatDepositInvoiceContext quotes price with fc and creates a deposit invoice for the quoted amount.
+// The Quote is recorded on the returned DepositInvoice, also when creating the invoice fails. Repeating
+// this call quotes again, so a failed call is retried with CreateQuotedDepositInvoiceContext, passing the
+// recorded Quote and WithIdempotencyKey of the invoice's IdempotencyKey, for the same amount.
+func (pc *PlatformClient) CreateFiatDepositInvoiceContext(ctx context.Context, fc *FiatConverter, price FiatAmount, label, network string, opts ...CallOption) (DepositInvoice, error) {
+	pc.logger.Infof("Quoting Deposit Invoice of %s", price)
+	quote, err := fc.Quote(pc.callContext(ctx), price)
+	if err != nil {
+		pc.logger.Errorf("Quote Failed: %s", err.Error())
+		return DepositInvoice{}, err
+	}
+	pc.logger.Infof("Quoted %s as %s at %s", price, quote.Amount, quote.Rate)
+	return pc.CreateQuotedDepositInvoiceContext(ctx, quote, label, network, opts...)
+}
+
+// CreateQuotedDepositInvoice creates a deposit invoice for the amount of a quote, see CreateQuotedDepositInvoiceContext
+func (pc *PlatformClient) CreateQuotedDepositInvoice(quote Quote, label, network string) (DepositInvoice, error) {
+	return pc.CreateQuotedDepositInvoiceContext(pc.Context, quote, label, network)
+}
+
+// CreateQuotedDepositInvoiceContext creates a deposit invoice for the amount of an unexpired quote,
+// recording the quote on the returned DepositInvoice as CreateFiatDepositInvoiceContext does
+func (pc *PlatformClient) CreateQuotedDepositInvoiceContext(ctx context.Context, quote Quote, label, network string, opts ...CallOption) (DepositInvoice, error) {
+	if quote.Expired(time.Now()) {
+		pc.logger.Errorf("Quote %s Expired", quote.Id)
+		return DepositInvoice{Quote: &quote}, invalid("quote %s expired at %s", quote.Id, quote.ExpiresAt.Format(time.RFC3339))
+	}
+	invoice, err := pc.CreateDepositInvoiceContext(ctx, quote.Amount, label, network, opts...)
+	quote.InvoiceId = invoice.Id
+	invoice.Quote = &quote
+	return invoice, err
+}
@@ -0,0 +1,236 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultThrottlePause is how long the limiter pauses after a 429 without a Retry-After header
+	defaultThrottlePause = time.Second
+	// minThrottleFactor bounds how far a 429 can lower a bucket's rate, as a divisor of its configured rate
+	minThrottleFactor = 8
+)
+
+// RateLimit is a token bucket allowing Rate requests per second on average, in bursts of up to Burst requests
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (rl RateLimit) validate() error {
+	if rl.Rate <= 0 {
+		return fmt.Errorf("platform: rate limit must be positive, got %v", rl.Rate)
+	}
+	if rl.Burst < 1 {
+		return fmt.Errorf("platform: rate limit burst must be at least 1, got %d", rl.Burst)
+	}
+	return nil
+}
+
+// WithRateLimit limits the requests sent by the client, across all endpoints and goroutines.
+// Every attempt, including retries, takes a token.
+func WithRateLimit(limit RateLimit) Option {
+	return func(o *options) error {
+		if err := limit.validate(); err != nil {
+			return err
+		}
+		o.rateLimit = &limit
+		return nil
+	}
+}
+
+// WithEndpointRateLimit limits the requests sent to the endpoints of group.
+// It applies on top of the limit set with WithRateLimit.
+func WithEndpointRateLimit(group EndpointGroup, limit RateLimit) Option {
+	return func(o *options) error {
+		if err := limit.validate(); err != nil {
+			return err
+		}
+		switch group {
+		case GroupReads, GroupWithdrawals, GroupWrites:
+		default:
+			return fmt.Errorf("platform: unknown endpoint group %q", group)
+		}
+		if o.groupRateLimits == nil {
+			o.groupRateLimits = make(map[EndpointGroup]RateLimit)
+		}
+		o.groupRateLimits[group] = limit
+		return nil
+	}
+}
+
+// tokenBucket implements a RateLimit. Its rate is lowered when the server throttles requests
+// and recovers to the configured rate as requests succeed.
+type tokenBucket struct {
+	mu     sync.Mutex
+	limit  RateLimit
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	return &tokenBucket{
+		limit:  limit,
+		rate:   limit.Rate,
+		tokens: float64(limit.Burst),
+	}
+}
+
+// reserve takes a token and returns how long the caller must wait before using it
+func (tb *tokenBucket) reserve(now time.Time) time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if !tb.last.IsZero() {
+		tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+		if tb.tokens > float64(tb.limit.Burst) {
+			tb.tokens = float64(tb.limit.Burst)
+		}
+	}
+	tb.last = now
+	tb.tokens--
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+// cancel returns a token taken by reserve that was not used
+func (tb *tokenBucket) cancel() {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.tokens++
+}
+
+// throttle halves the bucket's rate after the server returned 429
+func (tb *tokenBucket) throttle() {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.rate /= 2
+	if floor := tb.limit.Rate / minThrottleFactor; tb.rate < floor {
+		tb.rate = floor
+	}
+}
+
+// recover raises a throttled rate back towards the configured rate
+func (tb *tokenBucket) recover() {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if tb.rate < tb.limit.Rate {
+		tb.rate += tb.limit.Rate / 10
+		if tb.rate > tb.limit.Rate {
+			tb.rate = tb.limit.Rate
+		}
+	}
+}
+
+// rateLimiter holds the global and per group token buckets of a client.
+// It is safe for concurrent use and may be shared between clients.
+type rateLimiter struct {
+	global *tokenBucket
+	groups map[EndpointGroup]*tokenBucket
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+// newRateLimiter returns nil if no limit is configured
+func newRateLimiter(global *RateLimit, groups map[EndpointGroup]RateLimit) *rateLimiter {
+	if global == nil && len(groups) == 0 {
+		return nil
+	}
+	rl := &rateLimiter{
+		groups: make(map[EndpointGroup]*tokenBucket),
+	}
+	if global != nil {
+		rl.global = newTokenBucket(*global)
+	}
+	for group, limit := range groups {
+		rl.groups[group] = newTokenBucket(limit)
+	}
+	return rl
+}
+
+func (rl *rateLimiter) buckets(endpoint Endpoint) []*tokenBucket {
+	buckets := make([]*tokenBucket, 0, 2)
+	if rl.global != nil {
+		buckets = append(buckets, rl.global)
+	}
+	if tb, ok := rl.groups[endpoint.Group()]; ok {
+		buckets = append(buckets, tb)
+	}
+	return buckets
+}
+
+// wait blocks until a request to endpoint is allowed. It returns early, without taking a token,
+// if ctx is done or its deadline would pass before the request is allowed.
+func (rl *rateLimiter) wait(ctx context.Context, endpoint Endpoint) error {
+	now := time.Now()
+	buckets := rl.buckets(endpoint)
+	var delay time.Duration
+	for _, tb := range buckets {
+		if d := tb.reserve(now); d > delay {
+			delay = d
+		}
+	}
+	rl.mu.Lock()
+	if pause := rl.pausedUntil.Sub(now); pause > delay {
+		delay = pause
+	}
+	rl.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	cancel := func() {
+		for _, tb := range buckets {
+			tb.cancel()
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+		cancel()
+		return fmt.Errorf("platform: rate limit wait of %s exceeds the context deadline: %w", delay, context.DeadlineExceeded)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// observe adapts the limiter to the response of an attempt
+func (rl *rateLimiter) observe(endpoint Endpoint, res *http.Response) {
+	if res == nil {
+		return
+	}
+	buckets := rl.buckets(endpoint)
+	if res.StatusCode != http.StatusTooManyRequests {
+		if res.StatusCode < http.StatusBadRequest {
+			for _, tb := range buckets {
+				tb.recover()
+			}
+		}
+		return
+	}
+
+	pause, ok := parseRetryAfter(res.Header.Get("Retry-After"))
+	if !ok {
+		pause = defaultThrottlePause
+	}
+	rl.mu.Lock()
+	if until := time.Now().Add(pause); until.After(rl.pausedUntil) {
+		rl.pausedUntil = until
+	}
+	rl.mu.Unlock()
+	for _, tb := range buckets {
+		tb.throttle()
+	}
+}
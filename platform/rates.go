@@ -0,0 +1,399 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRateCacheTTL is how long an HTTPRateProvider reuses a rate when no TTL is given
+const DefaultRateCacheTTL = time.Minute
+
+// ErrNoRate is returned when a RateProvider has no rate for a currency at the requested time
+var ErrNoRate = errors.New("platform: no exchange rate")
+
+// Rate is the price of one bitcoin in a fiat currency
+type Rate struct {
+	// Currency is the ISO 4217 code of the fiat currency, such as USD
+	Currency string
+	// Time is when the rate was observed
+	Time   time.Time
+	perBTC *big.Rat
+}
+
+// ParseRate returns the Rate of one bitcoin at perBTC units of currency, a decimal such as "64000.12"
+func ParseRate(currency, perBTC string, at time.Time) (Rate, error) {
+	code, err := currencyCode(currency)
+	if err != nil {
+		return Rate{}, err
+	}
+	perBTC = strings.TrimSpace(perBTC)
+	price, ok := new(big.Rat).SetString(perBTC)
+	// big.Rat also parses fractions, which have no exact decimal
+	if !ok || price.Sign() <= 0 || strings.Contains(perBTC, "/") {
+		return Rate{}, fmt.Errorf("platform: invalid %s rate %q", code, perBTC)
+	}
+	return Rate{Currency: code, Time: at, perBTC: price}, nil
+}
+
+// PerBTC returns the price of one bitcoin as an exact decimal, or "" for the zero Rate
+func (r Rate) PerBTC() string {
+	if r.perBTC == nil {
+		return ""
+	}
+	// a rate parsed from a decimal is exact with as many digits as its denominator takes to become 1
+	digits := 0
+	for x := new(big.Rat).Set(r.perBTC); !x.IsInt(); digits++ {
+		x.Mul(x, big.NewRat(10, 1))
+	}
+	return r.perBTC.FloatString(digits)
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("%s %s/BTC at %s", r.PerBTC(), r.Currency, r.Time.UTC().Format(time.RFC3339))
+}
+
+// rateJSON is the JSON encoding of a Rate
+type rateJSON struct {
+	Currency string    `json:"currency"`
+	Time     time.Time `json:"time"`
+	PerBTC   string    `json:"per_btc"`
+}
+
+// MarshalJSON encodes the Rate with its price as an exact decimal string, so that it can be stored
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rateJSON{Currency: r.Currency, Time: r.Time, PerBTC: r.PerBTC()})
+}
+
+// UnmarshalJSON decodes a Rate encoded by MarshalJSON
+func (r *Rate) UnmarshalJSON(b []byte) error {
+	var rj rateJSON
+	if err := json.Unmarshal(b, &rj); err != nil {
+		return err
+	}
+	if rj.Currency == "" && rj.PerBTC == "" {
+		*r = Rate{Time: rj.Time}
+		return nil
+	}
+	parsed, err := ParseRate(rj.Currency, rj.PerBTC, rj.Time)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// currencyCode normalizes an ISO 4217 currency code
+func currencyCode(currency string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(currency))
+	if len(code) != 3 || strings.IndexFunc(code, func(r rune) bool { return r < 'A' || r > 'Z' }) >= 0 {
+		return "", fmt.Errorf("platform: invalid currency %q", currency)
+	}
+	return code, nil
+}
+
+// RateProvider supplies bitcoin exchange rates. Implementations must be safe for concurrent use.
+type RateProvider interface {
+	// Rate returns the price of one bitcoin in currency at time at, or an error matching ErrNoRate if it has none
+	Rate(ctx context.Context, currency string, at time.Time) (Rate, error)
+}
+
+// noRate returns an error matching ErrNoRate
+func noRate(currency string, at time.Time) error {
+	return fmt.Errorf("%w for %s at %s", ErrNoRate, currency, at.UTC().Format(time.RFC3339))
+}
+
+// StaticRateProvider returns a fixed rate per currency, whatever the time asked for
+type StaticRateProvider struct {
+	rates map[string]Rate
+}
+
+// NewStaticRateProvider returns a StaticRateProvider of rates, at most one per currency
+func NewStaticRateProvider(rates ...Rate) (*StaticRateProvider, error) {
+	sp := &StaticRateProvider{rates: make(map[string]Rate, len(rates))}
+	for _, r := range rates {
+		if r.perBTC == nil {
+			return nil, errors.New("platform: rate not created by ParseRate")
+		}
+		if _, ok := sp.rates[r.Currency]; ok {
+			return nil, fmt.Errorf("platform: duplicate %s rate", r.Currency)
+		}
+		sp.rates[r.Currency] = r
+	}
+	return sp, nil
+}
+
+func (sp *StaticRateProvider) Rate(_ context.Context, currency string, at time.Time) (Rate, error) {
+	code, err := currencyCode(currency)
+	if err != nil {
+		return Rate{}, err
+	}
+	r, ok := sp.rates[code]
+	if !ok {
+		return Rate{}, noRate(code, at)
+	}
+	return r, nil
+}
+
+// CSVRateProvider looks rates up in a history loaded from CSV, returning the latest rate observed at or before
+// the time asked for
+type CSVRateProvider struct {
+	// history holds the rates of each currency sorted by time
+	history map[string][]Rate
+}
+
+// NewCSVRateProvider reads a rate history from r. Each record holds a time, a currency and the price of one bitcoin,
+// such as "2024-03-01T00:00:00Z,USD,61234.50". Times are RFC 3339 timestamps, dates (YYYY-MM-DD, UTC) or Unix seconds.
+// A first record whose time does not parse is taken as a header and skipped.
+func NewCSVRateProvider(r io.Reader) (*CSVRateProvider, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	cp := &CSVRateProvider{history: make(map[string][]Rate)}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("platform: reading rates: %w", err)
+		}
+		at, err := parseRateTime(record[0])
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("platform: rates line %d: %w", line, err)
+		}
+		rate, err := ParseRate(record[1], record[2], at)
+		if err != nil {
+			return nil, fmt.Errorf("platform: rates line %d: %w", line, err)
+		}
+		cp.history[rate.Currency] = append(cp.history[rate.Currency], rate)
+	}
+	for currency, rates := range cp.history {
+		sort.SliceStable(rates, func(i, j int) bool { return rates[i].Time.Before(rates[j].Time) })
+		cp.history[currency] = rates
+	}
+	return cp, nil
+}
+
+// OpenCSVRateProvider reads a rate history from the CSV file at path, see NewCSVRateProvider
+func OpenCSVRateProvider(path string) (*CSVRateProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("platform: opening rates: %w", err)
+	}
+	defer f.Close()
+	return NewCSVRateProvider(f)
+}
+
+// parseRateTime parses the time of a CSV rate
+func parseRateTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func (cp *CSVRateProvider) Rate(_ context.Context, currency string, at time.Time) (Rate, error) {
+	code, err := currencyCode(currency)
+	if err != nil {
+		return Rate{}, err
+	}
+	rates := cp.history[code]
+	// the first rate observed after at
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].Time.After(at) })
+	if i == 0 {
+		return Rate{}, noRate(code, at)
+	}
+	return rates[i-1], nil
+}
+
+// HTTPRateConfig configures an HTTPRateProvider
+type HTTPRateConfig struct {
+	// URL is the rate endpoint. {currency} is replaced by the currency code, {date} by the date (YYYY-MM-DD, UTC)
+	// and {timestamp} by the Unix time asked for, such as "https://api.coinbase.com/v2/prices/BTC-{currency}/spot?date={date}".
+	// Rates fetched by {date} alone are stamped with the start of their day.
+	// Without {date} or {timestamp} the endpoint is taken to serve current rates, which are only used for times
+	// within CacheTTL of now. Other times have no rate
+	URL string
+	// Field is the dot separated path of the price in the JSON response, such as "data.amount".
+	// The price may be a JSON number or a decimal string
+	Field string
+	// Header is sent with every request, for API keys
+	Header http.Header
+	// HTTPClient sends the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+	// CacheTTL is how long a rate is reused, DefaultRateCacheTTL if zero
+	CacheTTL time.Duration
+}
+
+// HTTPRateProvider fetches rates from an HTTP JSON endpoint, caching them
+type HTTPRateProvider struct {
+	config     HTTPRateConfig
+	historical bool
+	// daily is set when the URL has {date} but no {timestamp}, so that a rate holds for a whole day
+	daily bool
+	path  []string
+
+	mu    sync.Mutex
+	cache map[string]cachedRate
+}
+
+type cachedRate struct {
+	rate    Rate
+	expires time.Time
+}
+
+// NewHTTPRateProvider returns an HTTPRateProvider configured by config
+func NewHTTPRateProvider(config HTTPRateConfig) (*HTTPRateProvider, error) {
+	if !strings.Contains(config.URL, "{currency}") {
+		return nil, errors.New("platform: rate URL has no {currency}")
+	}
+	if _, err := url.Parse(strings.NewReplacer("{currency}", "USD", "{date}", "2009-01-03", "{timestamp}", "0").Replace(config.URL)); err != nil {
+		return nil, fmt.Errorf("platform: invalid rate URL: %w", err)
+	}
+	if config.Field == "" {
+		return nil, errors.New("platform: no rate field")
+	}
+	if config.CacheTTL < 0 {
+		return nil, fmt.Errorf("platform: rate cache TTL must not be negative, got %s", config.CacheTTL)
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = DefaultRateCacheTTL
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	return &HTTPRateProvider{
+		config:     config,
+		historical: strings.Contains(config.URL, "{date}") || strings.Contains(config.URL, "{timestamp}"),
+		daily:      !strings.Contains(config.URL, "{timestamp}"),
+		path:       strings.Split(config.Field, "."),
+		cache:      make(map[string]cachedRate),
+	}, nil
+}
+
+func (hp *HTTPRateProvider) Rate(ctx context.Context, currency string, at time.Time) (Rate, error) {
+	code, err := currencyCode(currency)
+	if err != nil {
+		return Rate{}, err
+	}
+	now := time.Now()
+	observed := now
+	if hp.historical {
+		// the rate is that of the time the URL asks for, not of at
+		observed = at.UTC().Truncate(time.Second)
+		if hp.daily {
+			y, m, d := at.UTC().Date()
+			observed = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		}
+	} else if at.Before(now.Add(-hp.config.CacheTTL)) || at.After(now.Add(hp.config.CacheTTL)) {
+		return Rate{}, noRate(code, at)
+	}
+	u := strings.NewReplacer(
+		"{currency}", url.PathEscape(code),
+		"{date}", at.UTC().Format("2006-01-02"),
+		"{timestamp}", strconv.FormatInt(at.Unix(), 10),
+	).Replace(hp.config.URL)
+
+	hp.mu.Lock()
+	cached, ok := hp.cache[u]
+	hp.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.rate, nil
+	}
+
+	price, err := hp.fetch(ctx, u)
+	if err != nil {
+		return Rate{}, err
+	}
+	rate, err := ParseRate(code, price, observed)
+	if err != nil {
+		return Rate{}, err
+	}
+
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	for key, c := range hp.cache {
+		if !now.Before(c.expires) {
+			delete(hp.cache, key)
+		}
+	}
+	hp.cache[u] = cachedRate{rate: rate, expires: now.Add(hp.config.CacheTTL)}
+	return rate, nil
+}
+
+// fetch requests u and returns the price at the configured field
+func (hp *HTTPRateProvider) fetch(ctx context.Context, u string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return "", err
+	}
+	for key, values := range hp.config.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := hp.config.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("platform: fetching rate: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := readBody(res.Body, DefaultMaxResponseBytes)
+	if err != nil {
+		return "", fmt.Errorf("platform: reading rate: %w", err)
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s responded %s", ErrNoRate, req.URL.Redacted(), res.Status)
+	}
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return "", fmt.Errorf("platform: fetching rate: %s responded %s", req.URL.Redacted(), res.Status)
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("platform: decoding rate: %w", err)
+	}
+	for _, key := range hp.path {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("platform: rate response has no %s", hp.config.Field)
+		}
+		if v, ok = obj[key]; !ok {
+			return "", fmt.Errorf("platform: rate response has no %s", hp.config.Field)
+		}
+	}
+	switch price := v.(type) {
+	case json.Number:
+		return price.String(), nil
+	case string:
+		return price, nil
+	}
+	return "", fmt.Errorf("platform: rate %s is not a number", hp.config.Field)
+}
@@ -0,0 +1,190 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultCachedEndpoints are the endpoints cached by WithReadCache when it is given none
+var DefaultCachedEndpoints = []Endpoint{EndpointAccountBalance, EndpointGetSubscribedWebhook}
+
+// invalidates lists the cached endpoints made stale by each mutating endpoint
+var invalidates = map[Endpoint][]Endpoint{
+	EndpointInitiateWithdrawal:   {EndpointAccountBalance, EndpointGetWithdrawal},
+	EndpointCreateDepositInvoice: {EndpointGetDepositInvoices},
+	EndpointSubscribeToWebhook:   {EndpointGetSubscribedWebhook},
+	EndpointDeleteWebhook:        {EndpointGetSubscribedWebhook},
+}
+
+// WithReadCache caches the successful responses of endpoints, or DefaultCachedEndpoints, for ttl.
+// Entries are dropped as soon as a call that changes them is made, such as InitiateWithdrawal for AccountBalance.
+func WithReadCache(ttl time.Duration, endpoints ...Endpoint) Option {
+	return func(o *options) error {
+		if ttl <= 0 {
+			return fmt.Errorf("platform: cache ttl must be positive, got %s", ttl)
+		}
+		if len(endpoints) == 0 {
+			endpoints = DefaultCachedEndpoints
+		}
+		cached := make(map[Endpoint]bool, len(endpoints))
+		for _, e := range endpoints {
+			if e.Mutating() {
+				return fmt.Errorf("platform: cannot cache %s", e)
+			}
+			cached[e] = true
+		}
+		o.cacheTTL = ttl
+		o.cachedEndpoints = cached
+		return nil
+	}
+}
+
+// WithRequestCoalescing shares the response of a GET request between all identical calls made while it is in flight.
+// A call whose leader fails because the leader's Context ended makes its own request instead.
+func WithRequestCoalescing() Option {
+	return func(o *options) error {
+		o.coalesceRequests = true
+		return nil
+	}
+}
+
+// readCache coalesces identical in-flight reads and caches read responses
+type readCache struct {
+	ttl       time.Duration
+	endpoints map[Endpoint]bool
+	coalesce  bool
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	inflight map[string]*flight
+	// generations count the invalidations of each endpoint, so that a read overlapping one is not cached
+	generations map[Endpoint]uint64
+}
+
+type cacheEntry struct {
+	endpoint Endpoint
+	body     []byte
+	expires  time.Time
+}
+
+// flight is a read in progress that identical calls wait for
+type flight struct {
+	endpoint Endpoint
+	done     chan struct{}
+	body     []byte
+	err      error
+}
+
+// newReadCache returns nil if neither caching nor coalescing is enabled
+func newReadCache(ttl time.Duration, endpoints map[Endpoint]bool, coalesce bool) *readCache {
+	if ttl <= 0 && !coalesce {
+		return nil
+	}
+	return &readCache{
+		ttl:       ttl,
+		endpoints: endpoints,
+		coalesce:  coalesce,
+		entries:   make(map[string]cacheEntry),
+		inflight:  make(map[string]*flight),
+
+		generations: make(map[Endpoint]uint64),
+	}
+}
+
+// invalidate drops the entries made stale by a call to endpoint, and detaches the reads of them in flight
+// so that later calls neither wait for them nor cache their responses
+func (rc *readCache) invalidate(endpoint Endpoint) {
+	stale := invalidates[endpoint]
+	if len(stale) == 0 {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for _, e := range stale {
+		rc.generations[e]++
+		for key, entry := range rc.entries {
+			if entry.endpoint == e {
+				delete(rc.entries, key)
+			}
+		}
+		for key, f := range rc.inflight {
+			if f.endpoint == e {
+				delete(rc.inflight, key)
+			}
+		}
+	}
+}
+
+// middleware serves cached and in-flight reads, and invalidates the cache before and after mutating calls.
+// Invalidating before keeps reads made during the call from being cached, as they may miss its effect
+func (rc *readCache) middleware(pc *PlatformClient) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			if call.Endpoint.Mutating() {
+				rc.invalidate(call.Endpoint)
+				defer rc.invalidate(call.Endpoint)
+				return next(ctx, call)
+			}
+			if call.Request.Method != http.MethodGet {
+				return next(ctx, call)
+			}
+
+			cacheable := rc.ttl > 0 && rc.endpoints[call.Endpoint]
+			key := call.Request.URL.String()
+			rc.mu.Lock()
+			generation := rc.generations[call.Endpoint]
+			if entry, ok := rc.entries[key]; ok && cacheable {
+				if time.Now().Before(entry.expires) {
+					rc.mu.Unlock()
+					return pc.decodeBody(call, entry.body)
+				}
+				delete(rc.entries, key)
+			}
+			if f, ok := rc.inflight[key]; ok && rc.coalesce {
+				rc.mu.Unlock()
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-f.done:
+				}
+				if f.err == nil {
+					return pc.decodeBody(call, f.body)
+				}
+				if errors.Is(f.err, context.Canceled) || errors.Is(f.err, context.DeadlineExceeded) {
+					return next(ctx, call)
+				}
+				return f.err
+			}
+			var f *flight
+			if rc.coalesce {
+				f = &flight{endpoint: call.Endpoint, done: make(chan struct{})}
+				rc.inflight[key] = f
+			}
+			rc.mu.Unlock()
+
+			err := next(ctx, call)
+
+			rc.mu.Lock()
+			if f != nil {
+				f.body, f.err = call.body, err
+				if rc.inflight[key] == f {
+					delete(rc.inflight, key)
+				}
+				close(f.done)
+			}
+			if err == nil && cacheable && rc.generations[call.Endpoint] == generation {
+				rc.entries[key] = cacheEntry{
+					endpoint: call.Endpoint,
+					body:     call.body,
+					expires:  time.Now().Add(rc.ttl),
+				}
+			}
+			rc.mu.Unlock()
+			return err
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package platform
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// rateLimitHeaders are the response headers that may carry the rate-limit budget, in order of preference
+var (
+	rateLimitLimitHeaders     = []string{"X-RateLimit-Limit", "RateLimit-Limit"}
+	rateLimitRemainingHeaders = []string{"X-RateLimit-Remaining", "RateLimit-Remaining"}
+	rateLimitResetHeaders     = []string{"X-RateLimit-Reset", "RateLimit-Reset"}
+)
+
+// resetEpochThreshold separates rate-limit resets given as Unix times from those given in seconds from now
+const resetEpochThreshold = 1000000000
+
+// ResponseMeta describes the HTTP response a call's result was read from
+type ResponseMeta struct {
+	StatusCode int
+	// RequestID is the id River assigned to the request, to quote to River support
+	RequestID string
+	// Date is the server time of the response, zero if it carried no Date header
+	Date time.Time
+	// RateLimit is the rate-limit budget reported by the response, nil if it reported none
+	RateLimit *RateLimitStatus
+	// Attempts is the number of requests the call made
+	Attempts int
+	// Latency is how long the call took, retries included
+	Latency time.Duration
+	// Cached is set when the result was served by the read cache or a coalesced call, without a response of its own
+	Cached bool
+	// Header holds every header of the response
+	Header http.Header
+}
+
+// RateLimitStatus is the rate-limit budget reported by a response
+type RateLimitStatus struct {
+	// Limit is the number of requests allowed in the window, 0 if not reported
+	Limit int
+	// Remaining is the number of requests left in the window
+	Remaining int
+	// Reset is when the window resets, zero if not reported
+	Reset time.Time
+}
+
+// WithResponseMeta fills meta with the metadata of the call's response once the call returns,
+// whether it succeeded or failed with an APIError. A call that failed without a response leaves StatusCode zero,
+// and a call of a client created WithDryRun leaves meta untouched.
+func WithResponseMeta(meta *ResponseMeta) CallOption {
+	return func(co *callOptions) {
+		co.meta = meta
+	}
+}
+
+// newResponseMeta returns the metadata of a completed call
+func newResponseMeta(call *Call, latency time.Duration, err error) ResponseMeta {
+	meta := ResponseMeta{
+		Attempts: call.Attempts,
+		Latency:  latency,
+	}
+	res := call.Response
+	if res == nil {
+		meta.Cached = err == nil
+		return meta
+	}
+	meta.StatusCode = res.StatusCode
+	meta.RequestID = requestID(res.Header)
+	meta.Header = res.Header.Clone()
+	if date, err := http.ParseTime(res.Header.Get("Date")); err == nil {
+		meta.Date = date
+	}
+	meta.RateLimit = parseRateLimit(res.Header, meta.Date)
+	return meta
+}
+
+// requestID returns the id River assigned to a request, or "" if the response carried none
+func requestID(h http.Header) string {
+	for _, header := range requestIDHeaders {
+		if id := h.Get(header); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// parseRateLimit parses the rate-limit headers of a response sent at date, which may be zero
+func parseRateLimit(h http.Header, date time.Time) *RateLimitStatus {
+	remaining, ok := headerInt(h, rateLimitRemainingHeaders)
+	if !ok {
+		return nil
+	}
+	rl := &RateLimitStatus{Remaining: remaining}
+	rl.Limit, _ = headerInt(h, rateLimitLimitHeaders)
+	if reset, ok := headerInt(h, rateLimitResetHeaders); ok {
+		if reset >= resetEpochThreshold {
+			rl.Reset = time.Unix(int64(reset), 0)
+		} else {
+			// seconds from when the response was sent
+			if date.IsZero() {
+				date = time.Now()
+			}
+			rl.Reset = date.Add(time.Duration(reset) * time.Second)
+		}
+	}
+	return rl
+}
+
+// headerInt returns the first of headers that holds a non negative integer
+func headerInt(h http.Header, headers []string) (int, bool) {
+	for _, header := range headers {
+		value := h.Get(header)
+		if value == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,169 @@
+package platform
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header that marks a mutating request as safe to repeat
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy controls how sendRequest retries failed attempts.
+// Only GET requests and requests carrying an IdempotencyKeyHeader are retried,
+// and only after a transport error or a 408, 429, 502, 503 or 504 response.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay computed from InitialBackoff and Multiplier
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after every attempt
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, of each delay that is randomized
+	Jitter float64
+	// OnAttempt, if set, is called after every attempt
+	OnAttempt func(Attempt)
+}
+
+// Attempt describes one HTTP attempt made by sendRequest
+type Attempt struct {
+	// Number is 1 for the first attempt
+	Number int
+	Method string
+	URL    string
+	// StatusCode is 0 if no response was received
+	StatusCode int
+	// Err is the transport error of the attempt, if any
+	Err error
+	// Retrying reports whether another attempt will be made after Delay
+	Retrying bool
+	Delay    time.Duration
+}
+
+// DefaultRetryPolicy returns a RetryPolicy of 4 attempts backing off from 250ms to 5s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// WithRetryPolicy enables retries of safe requests according to policy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) error {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		o.retryPolicy = &policy
+		return nil
+	}
+}
+
+func (rp *RetryPolicy) validate() error {
+	if rp.MaxAttempts < 1 {
+		return errors.New("platform: retry policy needs at least 1 attempt")
+	}
+	if rp.InitialBackoff < 0 || rp.MaxBackoff < 0 {
+		return errors.New("platform: retry backoff must not be negative")
+	}
+	if rp.Multiplier < 1 {
+		return errors.New("platform: retry multiplier must be at least 1")
+	}
+	if rp.Jitter < 0 || rp.Jitter > 1 {
+		return errors.New("platform: retry jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// backoff returns the delay after the given attempt number
+func (rp *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(rp.InitialBackoff) * math.Pow(rp.Multiplier, float64(attempt-1))
+	if rp.MaxBackoff > 0 && delay > float64(rp.MaxBackoff) {
+		delay = float64(rp.MaxBackoff)
+	}
+	if rp.Jitter > 0 {
+		delay -= delay * rp.Jitter * jitterRand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// delay returns how long to wait before retrying after res, honoring Retry-After on 429 and 503
+func (rp *RetryPolicy) delay(attempt int, res *http.Response) time.Duration {
+	delay := rp.backoff(attempt)
+	if res != nil && (res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable) {
+		if after, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+			delay = after
+		}
+	}
+	return delay
+}
+
+// canRetry reports whether req may be sent more than once
+func canRetry(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	}
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+// shouldRetry reports whether the outcome of an attempt is worth retrying
+func shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return retryableStatus(res.StatusCode)
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		delay := time.Until(at)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// lockedSource is a rand.Source safe for concurrent use
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (ls *lockedSource) Int63() int64 {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.src.Int63()
+}
+
+func (ls *lockedSource) Seed(seed int64) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.src.Seed(seed)
+}
+
+var jitterRand = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
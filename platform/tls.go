@@ -0,0 +1,193 @@
+package platform
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	b64 "encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrPinMismatch is matched by the PinError returned when no certificate of a server has a pinned key
+var ErrPinMismatch = errors.New("platform: certificate pin mismatch")
+
+// PinError is returned when a server presents no certificate whose public key is pinned with WithPinnedKeys
+type PinError struct {
+	// Host is the server name sent to the server, empty when connecting to an IP address
+	Host string
+	// Presented are the pins of the certificates the server presented, in the format of WithPinnedKeys
+	Presented []string
+}
+
+func (e *PinError) Error() string {
+	host := ""
+	if e.Host != "" {
+		host = " for " + e.Host
+	}
+	return fmt.Sprintf("platform: certificate pin mismatch%s: server presented %s", host, strings.Join(e.Presented, ", "))
+}
+
+func (e *PinError) Is(target error) bool {
+	return target == ErrPinMismatch
+}
+
+// tlsOptions collects the TLS settings of the client
+type tlsOptions struct {
+	rootCAs      *x509.CertPool
+	certificates []tls.Certificate
+	pins         map[[sha256.Size]byte]bool
+	minVersion   uint16
+}
+
+// set reports whether any TLS setting was passed
+func (t *tlsOptions) set() bool {
+	return t.rootCAs != nil || len(t.certificates) > 0 || len(t.pins) > 0 || t.minVersion != 0
+}
+
+// WithRootCAs verifies the certificates of Platform API against pool instead of the system roots
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(o *options) error {
+		if pool == nil {
+			return errors.New("platform: nil root CA pool")
+		}
+		o.tls.rootCAs = pool
+		return nil
+	}
+}
+
+// WithRootCAFile verifies the certificates of Platform API against the PEM certificates in path instead of the system roots
+func WithRootCAFile(path string) Option {
+	return func(o *options) error {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("platform: reading root CAs: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("platform: no certificates in %s", path)
+		}
+		o.tls.rootCAs = pool
+		return nil
+	}
+}
+
+// WithClientCertificate presents cert to servers asking for one, for mutual TLS
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(o *options) error {
+		if len(cert.Certificate) == 0 || cert.PrivateKey == nil {
+			return errors.New("platform: client certificate has no certificate or private key")
+		}
+		o.tls.certificates = append(o.tls.certificates, cert)
+		return nil
+	}
+}
+
+// WithClientCertificateFile loads a PEM client certificate and its key for mutual TLS
+func WithClientCertificateFile(certFile, keyFile string) Option {
+	return func(o *options) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("platform: loading client certificate: %w", err)
+		}
+		return WithClientCertificate(cert)(o)
+	}
+}
+
+// WithPinnedKeys only accepts servers with a certificate in their verified chain whose public key is pinned.
+// A pin is the base64 SHA-256 hash of a certificate's DER SubjectPublicKeyInfo, optionally prefixed with "sha256/",
+// as printed by SPKIPin. Pin a backup key too, so that River can rotate its certificate.
+func WithPinnedKeys(pins ...string) Option {
+	return func(o *options) error {
+		if len(pins) == 0 {
+			return errors.New("platform: no pinned keys")
+		}
+		if o.tls.pins == nil {
+			o.tls.pins = make(map[[sha256.Size]byte]bool, len(pins))
+		}
+		for _, pin := range pins {
+			b, err := b64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+			if err != nil || len(b) != sha256.Size {
+				return fmt.Errorf("platform: invalid pin %q, want a base64 SHA-256 hash", pin)
+			}
+			var hash [sha256.Size]byte
+			copy(hash[:], b)
+			o.tls.pins[hash] = true
+		}
+		return nil
+	}
+}
+
+// WithMinTLSVersion sets the minimum TLS version accepted, such as tls.VersionTLS13
+func WithMinTLSVersion(version uint16) Option {
+	return func(o *options) error {
+		switch version {
+		case tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13:
+		default:
+			return fmt.Errorf("platform: unknown TLS version %#04x", version)
+		}
+		o.tls.minVersion = version
+		return nil
+	}
+}
+
+// SPKIPin returns the pin of cert's public key in the format of WithPinnedKeys
+func SPKIPin(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + b64.StdEncoding.EncodeToString(hash[:])
+}
+
+// config returns base with the TLS settings applied. base is not modified
+func (t *tlsOptions) config(base *tls.Config) *tls.Config {
+	var c *tls.Config
+	if base != nil {
+		c = base.Clone()
+	} else {
+		c = &tls.Config{}
+	}
+	if t.rootCAs != nil {
+		c.RootCAs = t.rootCAs
+	}
+	if len(t.certificates) > 0 {
+		c.Certificates = append(c.Certificates, t.certificates...)
+	}
+	if t.minVersion != 0 {
+		c.MinVersion = t.minVersion
+	}
+	if len(t.pins) > 0 {
+		pins := t.pins
+		verify := c.VerifyConnection
+		c.VerifyConnection = func(cs tls.ConnectionState) error {
+			if verify != nil {
+				if err := verify(cs); err != nil {
+					return err
+				}
+			}
+			return verifyPins(pins, cs)
+		}
+	}
+	return c
+}
+
+// verifyPins checks that a certificate of the connection has a pinned key. Only the verified chains are checked,
+// as any certificate can be appended to the presented ones, unless verification is skipped
+func verifyPins(pins map[[sha256.Size]byte]bool, cs tls.ConnectionState) error {
+	chains := cs.VerifiedChains
+	if len(chains) == 0 {
+		chains = [][]*x509.Certificate{cs.PeerCertificates}
+	}
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if pins[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+				return nil
+			}
+		}
+	}
+	presented := make([]string, 0, len(cs.PeerCertificates))
+	for _, cert := range cs.PeerCertificates {
+		presented = append(presented, SPKIPin(cert))
+	}
+	return &PinError{Host: cs.ServerName, Presented: presented}
+}
@@ -0,0 +1,275 @@
+package platform
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentHeader is the W3C Trace Context header propagated on every request
+const TraceparentHeader = "traceparent"
+
+// Span attribute keys set by the tracing Middleware
+const (
+	AttributeEndpoint   = "platform.endpoint"
+	AttributeAccount    = "platform.account_id"
+	AttributeRetries    = "platform.retries"
+	AttributeMethod     = "http.method"
+	AttributeURL        = "http.url"
+	AttributeStatusCode = "http.status_code"
+)
+
+// Tracer starts a span for every call of a client.
+//
+// Tracer and Span mirror the subset of go.opentelemetry.io/otel/trace used by this package,
+// so that an OpenTelemetry adapter is a thin wrapper:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attrs ...platform.Attribute) (context.Context, platform.Span) {
+//		ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		s := otelSpan{span}
+//		s.SetAttributes(attrs...)
+//		return ctx, s
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SetAttributes(attrs ...platform.Attribute) {
+//		for _, a := range attrs {
+//			s.Span.SetAttributes(attribute.String(a.Key, fmt.Sprint(a.Value)))
+//		}
+//	}
+//	func (s otelSpan) RecordError(err error) { s.Span.RecordError(err) }
+//	func (s otelSpan) SetError(description string) { s.Span.SetStatus(codes.Error, description) }
+//	func (s otelSpan) End() { s.Span.End() }
+//	func (s otelSpan) SpanContext() platform.SpanContext {
+//		sc := s.Span.SpanContext()
+//		return platform.SpanContext{TraceID: sc.TraceID(), SpanID: sc.SpanID(), Sampled: sc.IsSampled()}
+//	}
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx, returning a Context holding the new span
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	// SetError marks the span as failed
+	SetError(description string)
+	// SpanContext returns the ids propagated in the traceparent header
+	SpanContext() SpanContext
+	End()
+}
+
+// Attribute is a key/value pair set on a Span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// SpanContext identifies a span across process boundaries, as in a W3C traceparent header
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid reports whether both ids are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Traceparent formats sc as a version 00 traceparent header value
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), flags)
+}
+
+// ParseTraceparent parses a traceparent header value, such as one received by an HTTP handler
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, fmt.Errorf("platform: invalid traceparent %q", value)
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, fmt.Errorf("platform: invalid traceparent %q", value)
+	}
+	traceID, err1 := hex.DecodeString(parts[1])
+	spanID, err2 := hex.DecodeString(parts[2])
+	flags, err3 := hex.DecodeString(parts[3])
+	if err1 != nil || err2 != nil || err3 != nil || len(traceID) != 16 || len(spanID) != 8 || len(flags) != 1 {
+		return sc, fmt.Errorf("platform: invalid traceparent %q", value)
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("platform: invalid traceparent %q", value)
+	}
+	return sc, nil
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a Context carrying sc as the parent of the spans started by the client.
+// It lets a traceparent received by an HTTP handler be continued without a Tracer.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the SpanContext set by ContextWithSpanContext or by a SimpleTracer span
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// WithTracer starts a span with tracer for every call of the client
+func WithTracer(tracer Tracer) Option {
+	return func(o *options) error {
+		if tracer == nil {
+			return errors.New("platform: nil tracer")
+		}
+		o.tracer = tracer
+		return nil
+	}
+}
+
+// tracingMiddleware starts a span per call and propagates it in the traceparent header.
+// Without a Tracer it propagates the SpanContext of the call's Context, if any.
+func tracingMiddleware(tracer Tracer) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			var span Span
+			if tracer != nil {
+				ctx, span = tracer.Start(ctx, "platform."+string(call.Endpoint),
+					Attribute{Key: AttributeEndpoint, Value: string(call.Endpoint)},
+					Attribute{Key: AttributeAccount, Value: call.AccountID},
+					Attribute{Key: AttributeMethod, Value: call.Request.Method},
+					Attribute{Key: AttributeURL, Value: call.Request.URL.String()},
+				)
+			}
+
+			sc, ok := SpanContextFromContext(ctx)
+			if span != nil && span.SpanContext().IsValid() {
+				sc, ok = span.SpanContext(), true
+			}
+			if ok {
+				call.Request.Header.Set(TraceparentHeader, sc.Traceparent())
+			}
+			if span == nil {
+				return next(ctx, call)
+			}
+
+			err := next(ctx, call)
+			if call.Response != nil {
+				span.SetAttributes(Attribute{Key: AttributeStatusCode, Value: call.Response.StatusCode})
+			}
+			span.SetAttributes(Attribute{Key: AttributeRetries, Value: retries(call)})
+			if err != nil {
+				span.RecordError(err)
+				span.SetError(err.Error())
+			}
+			span.End()
+			return err
+		}
+	}
+}
+
+func retries(call *Call) int {
+	if call.Attempts > 1 {
+		return call.Attempts - 1
+	}
+	return 0
+}
+
+// SimpleTracer is a Tracer that generates W3C ids and passes every finished span to a callback.
+// It continues the trace of a parent set with ContextWithSpanContext.
+type SimpleTracer struct {
+	onEnd func(FinishedSpan)
+}
+
+// FinishedSpan is a span ended by a SimpleTracer
+type FinishedSpan struct {
+	Name       string
+	Parent     SpanContext
+	Context    SpanContext
+	Attributes map[string]interface{}
+	Err        error
+	Start, End time.Time
+}
+
+// NewSimpleTracer returns a SimpleTracer calling onEnd, which may be nil, for every finished span
+func NewSimpleTracer(onEnd func(FinishedSpan)) *SimpleTracer {
+	return &SimpleTracer{onEnd: onEnd}
+}
+
+// Start implements Tracer
+func (st *SimpleTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	span := &simpleSpan{
+		tracer: st,
+		data: FinishedSpan{
+			Name:       name,
+			Attributes: make(map[string]interface{}),
+			Start:      time.Now(),
+		},
+	}
+	parent, ok := SpanContextFromContext(ctx)
+	if ok {
+		span.data.Parent = parent
+		span.data.Context.TraceID = parent.TraceID
+		span.data.Context.Sampled = parent.Sampled
+	} else {
+		_, _ = rand.Read(span.data.Context.TraceID[:])
+		span.data.Context.Sampled = true
+	}
+	_, _ = rand.Read(span.data.Context.SpanID[:])
+	span.SetAttributes(attrs...)
+	return ContextWithSpanContext(ctx, span.data.Context), span
+}
+
+type simpleSpan struct {
+	tracer *SimpleTracer
+	mu     sync.Mutex
+	data   FinishedSpan
+}
+
+func (s *simpleSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range attrs {
+		s.data.Attributes[a.Key] = a.Value
+	}
+}
+
+func (s *simpleSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err
+}
+
+func (s *simpleSpan) SetError(string) {}
+
+func (s *simpleSpan) SpanContext() SpanContext {
+	return s.data.Context
+}
+
+func (s *simpleSpan) End() {
+	s.mu.Lock()
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	if s.tracer.onEnd != nil {
+		s.tracer.onEnd(data)
+	}
+}
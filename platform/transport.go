@@ -0,0 +1,29 @@
+package platform
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// newTransport returns the transport of the client's http.Client with the TLS and proxy settings applied,
+// or nil if it needs no change. Only an *http.Transport can be configured
+func newTransport(o *options, current http.RoundTripper) (http.RoundTripper, error) {
+	if !o.tls.set() && o.proxy == nil && !o.accountProxies {
+		return nil, nil
+	}
+	if current == nil {
+		current = http.DefaultTransport
+	}
+	t, ok := current.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("platform: TLS and proxy options need an *http.Transport, the http client has a %T", current)
+	}
+	t = t.Clone()
+	if o.tls.set() {
+		t.TLSClientConfig = o.tls.config(t.TLSClientConfig)
+	}
+	if o.proxy != nil || o.accountProxies {
+		t.Proxy = proxyFunc(o.proxy, t.Proxy)
+	}
+	return t, nil
+}
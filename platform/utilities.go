@@ -2,85 +2,96 @@ package platform
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-
-	log "github.com/SachinMeier/platform-client-go/pkg/log"
 )
 
 type DecodedInvoice struct {
-	Amount  sats   `json:"amount"`
+	Amount  Amount `json:"amount"`
 	Memo    string `json:"memo"`
 	NodeId  string `json:"node_id"`
 	Invoice string `json:"destination"`
 }
 
 type FeeEstimate struct {
-	Amount  sats   `json:"amount"`
+	Amount  Amount `json:"amount"`
 	Invoice string `json:"destination"`
-	Fee     sats   `json:"fee"`
+	Fee     Amount `json:"fee"`
 }
 
 // DecodeInvoice decodes a Lightning Invoice using River Platform using `lncli decodepayreq`
 func (pc *PlatformClient) DecodeInvoice(invoice string) (DecodedInvoice, error) {
-	log.Infof("Query Decode Invoice %s", invoice)
+	return pc.DecodeInvoiceContext(pc.Context, invoice)
+}
+
+// DecodeInvoiceContext is DecodeInvoice bound to ctx
+func (pc *PlatformClient) DecodeInvoiceContext(ctx context.Context, invoice string, opts ...CallOption) (DecodedInvoice, error) {
+	pc.logger.Infof("Query Decode Invoice %s", invoice)
 	data := map[string]string{
 		"destination": invoice,
 	}
 
 	body, err := json.Marshal(data)
 	if err != nil {
-		log.Error("JSON encoding error")
+		pc.logger.Errorf("JSON encoding error")
 		return DecodedInvoice{}, err
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		pc.callContext(ctx),
 		"PUT",
 		fmt.Sprintf("%s/lightning/parse_invoice", pc.BaseURL),
 		bytes.NewBuffer(body),
 	)
 	if err != nil {
-		log.Error("Internal Error Creating Request")
+		pc.logger.Errorf("Internal Error Creating Request")
 		return DecodedInvoice{}, err
 	}
 
 	var decoded_invoice DecodedInvoice
-	err = pc.sendRequest(req, &decoded_invoice)
+	err = pc.sendRequest(EndpointDecodeInvoice, req, &decoded_invoice, opts...)
 	if err != nil {
-		log.Errorf("Invoice Decode Failed: %s", err.Error())
+		pc.logger.Errorf("Invoice Decode Failed: %s", err.Error())
 		return DecodedInvoice{}, err
 	}
 	return decoded_invoice, nil
 }
 
 // EstimateLightningFee estimates Lightning Fee of an invoice using `lncli`
-func (pc *PlatformClient) EstimateLightningFee(invoice string, amount sats) (FeeEstimate, error) {
-	log.Infof("Estimate fee for invoice %s", invoice)
+func (pc *PlatformClient) EstimateLightningFee(invoice string, amount Amount) (FeeEstimate, error) {
+	return pc.EstimateLightningFeeContext(pc.Context, invoice, amount)
+}
+
+// EstimateLightningFeeContext is EstimateLightningFee bound to ctx
+func (pc *PlatformClient) EstimateLightningFeeContext(ctx context.Context, invoice string, amount Amount, opts ...CallOption) (FeeEstimate, error) {
+	pc.logger.Infof("Estimate fee for invoice %s", invoice)
 	data := map[string]string{
 		"destination": invoice,
 	}
 
 	body, err := json.Marshal(data)
 	if err != nil {
-		log.Error("JSON encoding error")
+		pc.logger.Errorf("JSON encoding error")
 		return FeeEstimate{}, err
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		pc.callContext(ctx),
 		"PUT",
 		fmt.Sprintf("%s/lightning/estimate_fee/", pc.BaseURL),
 		bytes.NewBuffer(body),
 	)
 	if err != nil {
-		log.Error("Internal Error Creating Request")
+		pc.logger.Errorf("Internal Error Creating Request")
 		return FeeEstimate{}, err
 	}
 
 	var fee_estimate FeeEstimate
-	err = pc.sendRequest(req, &fee_estimate)
+	err = pc.sendRequest(EndpointEstimateLightningFee, req, &fee_estimate, opts...)
 	if err != nil {
-		log.Errorf("Invoice Decode Failed: %s", err.Error())
+		pc.logger.Errorf("Invoice Decode Failed: %s", err.Error())
 		return FeeEstimate{}, err
 	}
 	return fee_estimate, nil
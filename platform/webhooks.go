@@ -2,29 +2,30 @@ package platform
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-
-	log "github.com/SachinMeier/platform-client-go/pkg/log"
 )
 
 type Webhook struct {
 	Url     string `json:"url"`
 	Secret  string `json:"secret" default:""`
 	Enabled bool   `json:"enabled"`
+	// Simulated is set on the webhooks of a client created WithDryRun, which were never subscribed
+	Simulated bool `json:"-"`
 }
 
-func (pc *PlatformClient) handleWebhookRequest(req *http.Request, err error) (Webhook, error) {
+func (pc *PlatformClient) handleWebhookRequest(endpoint Endpoint, req *http.Request, err error, opts []CallOption) (Webhook, error) {
 	if err != nil {
-		log.Error("Internal Error")
+		pc.logger.Errorf("Internal Error")
 		return Webhook{}, err
 	}
 
 	var webhook Webhook
-	err = pc.sendRequest(req, &webhook)
+	err = pc.sendRequest(endpoint, req, &webhook, opts...)
 	if err != nil {
-		log.Error("Webhook Request Failed")
+		pc.logger.Errorf("Webhook Request Failed")
 		return Webhook{}, err
 	}
 	return webhook, nil
@@ -32,53 +33,90 @@ func (pc *PlatformClient) handleWebhookRequest(req *http.Request, err error) (We
 
 // SubscribeToWebhook subscribes to a webhook
 func (pc *PlatformClient) SubscribeToWebhook(callback_url string) (Webhook, error) {
-	log.Infof("Subscribing to Webhook %s", callback_url)
+	return pc.SubscribeToWebhookContext(pc.Context, callback_url)
+}
+
+// SubscribeToWebhookContext is SubscribeToWebhook bound to ctx
+func (pc *PlatformClient) SubscribeToWebhookContext(ctx context.Context, callback_url string, opts ...CallOption) (Webhook, error) {
+	pc.logger.Infof("Subscribing to Webhook %s", callback_url)
+	if pc.dryRun {
+		return pc.simulateWebhook(callback_url)
+	}
 
 	data := map[string]string{
 		"url": callback_url,
 	}
 	body, err := json.Marshal(data)
 	if err != nil {
-		log.Errorf("JSON encoding error with url: %s", callback_url)
+		pc.logger.Errorf("JSON encoding error with url: %s", callback_url)
 		return Webhook{}, err
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		pc.callContext(ctx),
 		"POST",
 		fmt.Sprintf("%s/accounts/%s/webhooks/", pc.BaseURL, pc.accountId),
 		bytes.NewBuffer(body),
 	)
-	return pc.handleWebhookRequest(req, err)
+	return pc.handleWebhookRequest(EndpointSubscribeToWebhook, req, err, opts)
 }
 
 // GetSubscribedWebhook queries subscribed webhook
 func (pc *PlatformClient) GetSubscribedWebhook() (Webhook, error) {
-	log.Infof("Querying Webhook")
-	req, err := http.NewRequest(
+	return pc.GetSubscribedWebhookContext(pc.Context)
+}
+
+// GetSubscribedWebhookContext is GetSubscribedWebhook bound to ctx
+func (pc *PlatformClient) GetSubscribedWebhookContext(ctx context.Context, opts ...CallOption) (Webhook, error) {
+	pc.logger.Infof("Querying Webhook")
+	req, err := http.NewRequestWithContext(
+		pc.callContext(ctx),
 		"GET",
 		fmt.Sprintf("%s/accounts/%s/webhooks/", pc.BaseURL, pc.accountId),
 		nil,
 	)
-	return pc.handleWebhookRequest(req, err)
+	return pc.handleWebhookRequest(EndpointGetSubscribedWebhook, req, err, opts)
 }
 
-// DeleteWebhook deletes the existing webhook
+// DeleteWebhook deletes the existing webhook. It returns false when the webhook was not deleted,
+// including in a dry run, see UnsubscribeWebhook for the reason
 func (pc *PlatformClient) DeleteWebhook() bool {
-	log.Infof("Querying Webhook")
-	req, err := http.NewRequest(
+	return pc.DeleteWebhookContext(pc.Context)
+}
+
+// DeleteWebhookContext is DeleteWebhook bound to ctx
+func (pc *PlatformClient) DeleteWebhookContext(ctx context.Context, opts ...CallOption) bool {
+	return pc.UnsubscribeWebhookContext(ctx, opts...) == nil
+}
+
+// UnsubscribeWebhook deletes the existing webhook, as DeleteWebhook does, returning why it failed.
+// A client created WithDryRun deletes nothing and returns ErrDryRun
+func (pc *PlatformClient) UnsubscribeWebhook() error {
+	return pc.UnsubscribeWebhookContext(pc.Context)
+}
+
+// UnsubscribeWebhookContext is UnsubscribeWebhook bound to ctx
+func (pc *PlatformClient) UnsubscribeWebhookContext(ctx context.Context, opts ...CallOption) error {
+	pc.logger.Infof("Querying Webhook")
+	if pc.dryRun {
+		pc.logger.Infof("Dry Run: Webhook Not Deleted")
+		return ErrDryRun
+	}
+	req, err := http.NewRequestWithContext(
+		pc.callContext(ctx),
 		"DELETE",
 		fmt.Sprintf("%s/accounts/%s/webhooks/", pc.BaseURL, pc.accountId),
 		nil,
 	)
 	if err != nil {
-		log.Error("Internal Error")
-		return false
+		pc.logger.Errorf("Internal Error")
+		return err
 	}
 
-	err = pc.sendRequest(req, nil)
+	err = pc.sendRequest(EndpointDeleteWebhook, req, nil, opts...)
 	if err != nil {
-		log.Error("Delete Webhook Failed")
-		return false
+		pc.logger.Errorf("Delete Webhook Failed")
+		return err
 	}
-	return true
+	return nil
 }
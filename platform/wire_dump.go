@@ -0,0 +1,175 @@
+package platform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// redactedHeaders are the headers whose values are never written to a wire dump
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// wireDumpSecretFields are the JSON fields whose values are never written to a wire dump
+var wireDumpSecretFields = map[string]bool{
+	"secret":        true,
+	"api_key":       true,
+	"api_secret":    true,
+	"password":      true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"preimage":      true,
+}
+
+// invoicePattern matches BOLT 11 invoices on any network
+var invoicePattern = regexp.MustCompile(`(?i)\b(lightning:)?ln(bc|tb|bcrt|tbs|sb)[0-9a-z]+\b`)
+
+// WireDumpOptions configures WithWireDump
+type WireDumpOptions struct {
+	// RedactInvoices redacts Lightning invoices in URLs and bodies, which reveal amounts and payees
+	RedactInvoices bool
+	// MaxBodyBytes truncates the bodies dumped, 0 dumps them whole
+	MaxBodyBytes int
+}
+
+// wireDump writes the requests and responses of every attempt to a Writer
+type wireDump struct {
+	opts WireDumpOptions
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// WithWireDump writes every request and response of the client to w, in HTTP/1.1 wire format, for debugging.
+// Credentials, cookies and webhook secrets are always redacted, invoices if opts.RedactInvoices is set.
+// Each attempt of a call is dumped, after the BeforeRequest hooks ran, and is written to w in one Write.
+func WithWireDump(w io.Writer, opts WireDumpOptions) Option {
+	return func(o *options) error {
+		if w == nil {
+			return errors.New("platform: nil wire dump writer")
+		}
+		if opts.MaxBodyBytes < 0 {
+			return fmt.Errorf("platform: max body bytes must not be negative, got %d", opts.MaxBodyBytes)
+		}
+		o.wireDump = &wireDump{w: w, opts: opts}
+		return nil
+	}
+}
+
+// dumpRequest returns the redacted dump of req, which must be called before req is sent
+func (wd *wireDump) dumpRequest(req *http.Request) []byte {
+	clone := req.Clone(req.Context())
+	clone.Header = redactHeader(req.Header)
+	var body []byte
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			body, _ = io.ReadAll(rc)
+			rc.Close()
+		}
+	}
+	clone.Body = nil
+	clone.GetBody = nil
+	clone.ContentLength = 0
+	head, err := httputil.DumpRequestOut(clone, false)
+	if err != nil {
+		return []byte(fmt.Sprintf("%s %s\r\n[dump failed: %s]\r\n", req.Method, req.URL, err.Error()))
+	}
+	if len(body) > 0 {
+		// DumpRequestOut drops Content-Length along with the body
+		head = bytes.Replace(head, []byte("\r\n\r\n"), []byte(fmt.Sprintf("\r\nContent-Length: %d\r\n\r\n", len(body))), 1)
+	}
+	return append(head, wd.redactBody(body)...)
+}
+
+// dumpResponse returns the redacted dump of res. At most limit bytes of its body are read,
+// and res.Body is replaced so that they can be read again
+func (wd *wireDump) dumpResponse(res *http.Response, limit int64) []byte {
+	body, err := io.ReadAll(io.LimitReader(res.Body, limit))
+	res.Body = &replayBody{Reader: io.MultiReader(bytes.NewReader(body), errReader{err}, res.Body), Closer: res.Body}
+
+	head := *res
+	head.Header = redactHeader(res.Header)
+	head.Body = http.NoBody
+	dump, dumpErr := httputil.DumpResponse(&head, false)
+	if dumpErr != nil {
+		dump = []byte(fmt.Sprintf("%s\r\n[dump failed: %s]\r\n", res.Status, dumpErr.Error()))
+	}
+	dump = append(dump, wd.redactBody(body)...)
+	if err != nil {
+		dump = append(dump, fmt.Sprintf("\r\n[reading body failed: %s]", err.Error())...)
+	}
+	return dump
+}
+
+// write writes the dump of one attempt
+func (wd *wireDump) write(call *Call, started time.Time, reqDump []byte, res *http.Response, err error, limit int64) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, ">>> %s attempt %d for %s at %s\n", call.Endpoint, call.Attempts, call.AccountID, started.UTC().Format(time.RFC3339Nano))
+	buf.Write(reqDump)
+	elapsed := time.Since(started).Round(time.Microsecond)
+	if err != nil {
+		fmt.Fprintf(&buf, "\n<<< failed after %s: %s\n\n", elapsed, wd.redactInvoices([]byte(err.Error())))
+	} else {
+		fmt.Fprintf(&buf, "\n<<< %s after %s\n", res.Status, elapsed)
+		buf.Write(wd.dumpResponse(res, limit))
+		buf.WriteString("\n\n")
+	}
+
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	_, _ = wd.w.Write(wd.redactInvoices(buf.Bytes()))
+}
+
+// redactBody redacts secrets in a JSON body and truncates it
+func (wd *wireDump) redactBody(body []byte) []byte {
+	if b, ok := redactJSON(body, wireDumpSecretFields); ok {
+		body = b
+	}
+	if wd.opts.MaxBodyBytes > 0 && len(body) > wd.opts.MaxBodyBytes {
+		body = append(body[:wd.opts.MaxBodyBytes:wd.opts.MaxBodyBytes], fmt.Sprintf("...[%d bytes]", len(body))...)
+	}
+	return body
+}
+
+// redactInvoices redacts the invoices in b if the dump is configured to
+func (wd *wireDump) redactInvoices(b []byte) []byte {
+	if !wd.opts.RedactInvoices {
+		return b
+	}
+	return invoicePattern.ReplaceAll(b, []byte(redacted))
+}
+
+// redactHeader returns a copy of h with credentials redacted
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range redactedHeaders {
+		if len(h.Values(name)) > 0 {
+			h.Set(name, redacted)
+		}
+	}
+	return h
+}
+
+// replayBody is a response body whose start has been read by a wire dump
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// errReader returns err once the body it follows is read, or io.EOF
+type errReader struct {
+	err error
+}
+
+func (er errReader) Read([]byte) (int, error) {
+	if er.err != nil {
+		return 0, er.err
+	}
+	return 0, io.EOF
+}
@@ -2,86 +2,120 @@ package platform
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-
-	log "github.com/SachinMeier/platform-client-go/pkg/log"
 )
 
 type WithdrawalDetail struct {
 	Network  string `json:"network"`
 	Invoice  string `json:"destination"`
-	FeeLimit int    `json:"fee_limit"`
+	FeeLimit Amount `json:"fee_limit"`
 }
 
 type Withdrawal struct {
-	Amount   int              `json:"amount"`
+	Amount   Amount           `json:"amount"`
 	Currency string           `json:"currency"`
 	Details  WithdrawalDetail `json:"withdrawal_details"`
 	State    string           `json:"state"`
 	Id       string           `json:"id"`
+	// IdempotencyKey is the key the withdrawal was initiated with. It is not part of the API response
+	IdempotencyKey string `json:"-"`
+	// Simulated is set on the withdrawals of a client created WithDryRun, which were never sent
+	Simulated bool `json:"-"`
 }
 
 type WithdrawalRequest struct {
-	Amount   sats
+	Amount   Amount
 	Invoice  string
-	FeeLimit sats
+	FeeLimit Amount
 	Currency string `default:"BTC"`
 	Network  string `default:"LN"`
+	// IdempotencyKey, if set, is sent with the withdrawal so that submitting the same request twice pays once
+	IdempotencyKey string
 }
 
 const (
-	LN              string = "LN"
-	BTC             string = "BTC"
-	DefaultFeeLimit sats   = 300
+	LN  string = "LN"
+	BTC string = "BTC"
 )
 
-func (pc *PlatformClient) handleWithdrawalRequest(req *http.Request, err error) (Withdrawal, error) {
+// DefaultFeeLimit is the fee limit of a WithdrawalRequest created by NewWithdrawalRequest
+var DefaultFeeLimit = Sats(300)
+
+func (pc *PlatformClient) handleWithdrawalRequest(endpoint Endpoint, req *http.Request, err error, opts []CallOption) (Withdrawal, error) {
 	if err != nil {
-		log.Error("Internal Error Creating Request")
+		pc.logger.Errorf("Internal Error Creating Request")
 		return Withdrawal{}, err
 	}
 
 	var withdrawal Withdrawal
-	err = pc.sendRequest(req, &withdrawal)
+	err = pc.sendRequest(endpoint, req, &withdrawal, opts...)
 	if err != nil {
-		log.Errorf("Querying Withdrawal Failed: %s", err.Error())
+		pc.logger.Errorf("Querying Withdrawal Failed: %s", err.Error())
 		return Withdrawal{}, err
 	}
 	return withdrawal, nil
 }
 
 // NewWithdrawalRequest returns a WithdrawalRequest object to be passed to SubmitWithdrawal
-func NewWithdrawalRequest(amount sats, invoice string) *WithdrawalRequest {
-	return &WithdrawalRequest{
-		Amount:   amount,
-		Invoice:  invoice,
-		FeeLimit: DefaultFeeLimit,
-		Currency: BTC,
-		Network:  LN,
-	}
+func NewWithdrawalRequest(amount Amount, invoice string) (*WithdrawalRequest, error) {
+	return NewWithdrawalRequestWithFeeLimit(amount, invoice, DefaultFeeLimit)
 }
 
 // NewWithdrawalRequest returns a WithdrawalRequest object with a defined fee_limit to be passed to SubmitWithdrawal
-func NewWithdrawalRequestWithFeeLimit(amount sats, invoice string, fee_limit sats) *WithdrawalRequest {
-	return &WithdrawalRequest{
-		Amount:   amount,
-		Invoice:  invoice,
-		FeeLimit: fee_limit,
-		Currency: BTC,
-		Network:  LN,
+// The request is given a fresh IdempotencyKey, and an error is returned if the system's random source fails.
+func NewWithdrawalRequestWithFeeLimit(amount Amount, invoice string, fee_limit Amount) (*WithdrawalRequest, error) {
+	key, err := NewIdempotencyKey()
+	if err != nil {
+		return nil, err
 	}
+	return &WithdrawalRequest{
+		Amount:         amount,
+		Invoice:        invoice,
+		FeeLimit:       fee_limit,
+		Currency:       BTC,
+		Network:        LN,
+		IdempotencyKey: key,
+	}, nil
 }
 
 // SubmitWithdrawalRequest takes a WithdrawalRequest and passes it to InitiateWithdrawal
 func (pc *PlatformClient) SubmitWithdrawalRequest(wreq *WithdrawalRequest) (Withdrawal, error) {
-	return pc.InitiateWithdrawal(wreq.Amount, wreq.Invoice, wreq.Currency, wreq.Network, wreq.FeeLimit)
+	return pc.SubmitWithdrawalRequestContext(pc.Context, wreq)
+}
+
+// SubmitWithdrawalRequestContext is SubmitWithdrawalRequest bound to ctx
+func (pc *PlatformClient) SubmitWithdrawalRequestContext(ctx context.Context, wreq *WithdrawalRequest, opts ...CallOption) (Withdrawal, error) {
+	if wreq.IdempotencyKey != "" {
+		opts = append([]CallOption{WithIdempotencyKey(wreq.IdempotencyKey)}, opts...)
+	}
+	return pc.InitiateWithdrawalContext(ctx, wreq.Amount, wreq.Invoice, wreq.Currency, wreq.Network, wreq.FeeLimit, opts...)
 }
 
-// InitiateWithdrawal initiates a withdrawal from River Platform API by paying a specific invoice
-func (pc *PlatformClient) InitiateWithdrawal(amount sats, invoice, currency, network string, fee_limit sats) (Withdrawal, error) {
-	log.Infof("Initiating Withdrawal: %d sats to %s", amount, invoice)
+// InitiateWithdrawal initiates a withdrawal from River Platform API by paying a specific invoice.
+// The withdrawal is sent with a generated idempotency key, see InitiateWithdrawalContext
+func (pc *PlatformClient) InitiateWithdrawal(amount Amount, invoice, currency, network string, fee_limit Amount) (Withdrawal, error) {
+	return pc.InitiateWithdrawalContext(pc.Context, amount, invoice, currency, network, fee_limit)
+}
+
+// InitiateWithdrawalContext is InitiateWithdrawal bound to ctx.
+// Unless WithIdempotencyKey is passed a key is generated. The key is set on the returned Withdrawal
+// even when an error is returned, so that a failed withdrawal can be retried without paying twice.
+func (pc *PlatformClient) InitiateWithdrawalContext(ctx context.Context, amount Amount, invoice, currency, network string, fee_limit Amount, opts ...CallOption) (Withdrawal, error) {
+	pc.logger.Infof("Initiating Withdrawal: %s to %s", amount, invoice)
+	key, opts, err := ensureIdempotencyKey(opts)
+	if err != nil {
+		pc.logger.Errorf("Idempotency Key Generation Failed: %s", err.Error())
+		return Withdrawal{}, err
+	}
+	if pc.dryRun {
+		withdrawal, err := pc.simulateWithdrawal(amount, invoice, currency, network, fee_limit)
+		withdrawal.IdempotencyKey = key
+		return withdrawal, err
+	}
+
 	data := map[string]interface{}{
 		"amount":   amount,
 		"currency": currency,
@@ -93,19 +127,27 @@ func (pc *PlatformClient) InitiateWithdrawal(amount sats, invoice, currency, net
 	}
 	body, err := json.Marshal(data)
 	if err != nil {
-		log.Error("JSON encoding error")
-		return Withdrawal{}, err
+		pc.logger.Errorf("JSON encoding error")
+		return Withdrawal{IdempotencyKey: key}, err
 	}
 
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/accounts/%s/withdrawals", pc.BaseURL, pc.accountId), bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(pc.callContext(ctx), "POST", fmt.Sprintf("%s/accounts/%s/withdrawals", pc.BaseURL, pc.accountId), bytes.NewBuffer(body))
 
-	return pc.handleWithdrawalRequest(req, err)
+	withdrawal, err := pc.handleWithdrawalRequest(EndpointInitiateWithdrawal, req, err, opts)
+	withdrawal.IdempotencyKey = key
+	return withdrawal, err
 }
 
 // GetWithdrawal returns a withdrawal based on the passed withdrawal_id
 func (pc *PlatformClient) GetWithdrawal(withdrawal_id string) (Withdrawal, error) {
-	log.Infof("Querying Withdrawal %s", withdrawal_id)
-	req, err := http.NewRequest(
+	return pc.GetWithdrawalContext(pc.Context, withdrawal_id)
+}
+
+// GetWithdrawalContext is GetWithdrawal bound to ctx
+func (pc *PlatformClient) GetWithdrawalContext(ctx context.Context, withdrawal_id string, opts ...CallOption) (Withdrawal, error) {
+	pc.logger.Infof("Querying Withdrawal %s", withdrawal_id)
+	req, err := http.NewRequestWithContext(
+		pc.callContext(ctx),
 		"GET",
 		fmt.Sprintf("%s/accounts/%s/withdrawals/%s",
 			pc.BaseURL,
@@ -113,5 +155,5 @@ func (pc *PlatformClient) GetWithdrawal(withdrawal_id string) (Withdrawal, error
 			withdrawal_id),
 		nil,
 	)
-	return pc.handleWithdrawalRequest(req, err)
+	return pc.handleWithdrawalRequest(EndpointGetWithdrawal, req, err, opts)
 }
@@ -0,0 +1,141 @@
+package platform
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// TestParseAmount checks the units, suffixes and precision accepted by ParseAmount
+func TestParseAmount(t *testing.T) {
+	cases := map[string]int64{
+		"0.0015 BTC":        150000000,
+		"0.0015btc":         150000000,
+		"21k sats":          21000000,
+		"21k":               21000000,
+		"1.5M sats":         1500000000,
+		"1500000 msat":      1500000,
+		"1Msat":             1,
+		"2100":              2100000,
+		" 2100 sat ":        2100000,
+		"0.001 sats":        1,
+		"1.500 satoshis":    1500,
+		"-42 sats":          -42000,
+		".5 sats":           500,
+		"0.00000000001 BTC": 1,
+	}
+	for s, msat := range cases {
+		a, err := platform.ParseAmount(s)
+		if err != nil {
+			t.Errorf("%q: %s", s, err.Error())
+		} else if a.Msats() != msat {
+			t.Errorf("%q: Incorrect Amount: %d msat, want %d", s, a.Msats(), msat)
+		}
+	}
+
+	for _, s := range []string{"", "sats", "1.2.3 sats", "1 dollar", "0.0001 sats", "0.000000000001 BTC", "1--2", "100000000 BTC", "1e5"} {
+		if a, err := platform.ParseAmount(s); err == nil {
+			t.Errorf("%q: parsed as %s", s, a)
+		}
+	}
+}
+
+// TestAmountArithmetic checks that arithmetic reports overflows
+func TestAmountArithmetic(t *testing.T) {
+	sum, err := platform.Sats(2100).Add(platform.Msats(500))
+	if err != nil || sum.Msats() != 2100500 {
+		t.Errorf("Incorrect Sum: %s, %v", sum, err)
+	}
+	diff, err := platform.Sats(100).Sub(platform.Sats(300))
+	if err != nil || diff != platform.Sats(-200) || diff.Sign() != -1 {
+		t.Errorf("Incorrect Difference: %s, %v", diff, err)
+	}
+	product, err := platform.Sats(21).Mul(1000)
+	if err != nil || product.Cmp(platform.MustParseAmount("21k")) != 0 {
+		t.Errorf("Incorrect Product: %s, %v", product, err)
+	}
+
+	max := platform.Msats(math.MaxInt64)
+	if _, err := max.Add(platform.Msats(1)); !errors.Is(err, platform.ErrAmountOverflow) {
+		t.Errorf("Incorrect Add Error: %v", err)
+	}
+	if _, err := platform.Msats(math.MinInt64).Sub(platform.Msats(1)); !errors.Is(err, platform.ErrAmountOverflow) {
+		t.Errorf("Incorrect Sub Error: %v", err)
+	}
+	if _, err := max.Mul(2); !errors.Is(err, platform.ErrAmountOverflow) {
+		t.Errorf("Incorrect Mul Error: %v", err)
+	}
+}
+
+// TestAmountFormat checks formatting in every unit
+func TestAmountFormat(t *testing.T) {
+	cases := []struct {
+		amount platform.Amount
+		unit   platform.Unit
+		want   string
+	}{
+		{platform.Sats(2100), platform.UnitSat, "2100 sats"},
+		{platform.Msats(500), platform.UnitSat, "0.5 sats"},
+		{platform.Sats(150000), platform.UnitBTC, "0.0015 BTC"},
+		{platform.Sats(150000), platform.UnitMsat, "150000000 msat"},
+		{platform.Sats(-1), platform.UnitBTC, "-0.00000001 BTC"},
+		{platform.Msats(math.MinInt64), platform.UnitMsat, "-9223372036854775808 msat"},
+	}
+	for _, c := range cases {
+		if got := c.amount.FormatUnit(c.unit); got != c.want {
+			t.Errorf("Incorrect Format: %q, want %q", got, c.want)
+		}
+		parsed, err := platform.ParseAmount(c.want)
+		if err != nil || parsed != c.amount {
+			t.Errorf("%q does not parse back: %s, %v", c.want, parsed, err)
+		}
+	}
+	if s := platform.Sats(21).String(); s != "21 sats" {
+		t.Errorf("Incorrect String: %q", s)
+	}
+}
+
+// TestAmountJSON checks that amounts are encoded as whole sats
+func TestAmountJSON(t *testing.T) {
+	b, err := json.Marshal(map[string]platform.Amount{"amount": platform.Sats(2100)})
+	if err != nil || string(b) != `{"amount":2100}` {
+		t.Errorf("Incorrect JSON: %s, %v", b, err)
+	}
+	if _, err := json.Marshal(platform.Msats(1500)); !errors.Is(err, platform.ErrInvalidRequest) {
+		t.Errorf("fraction of a sat encoded: %v", err)
+	}
+
+	var summary platform.AccountSummary
+	if err := json.Unmarshal([]byte(`{"balance": 2100, "available_balance": "0.00001 BTC"}`), &summary); err != nil {
+		t.Fatal(err.Error())
+	}
+	reserved, err := summary.ReservedBalance()
+	if err != nil || summary.Balance != platform.Sats(2100) || summary.AvailableBalance != platform.Sats(1000) ||
+		reserved != platform.Sats(1100) {
+		t.Errorf("Incorrect AccountSummary: %+v, %v", summary, err)
+	}
+	overflowing := platform.AccountSummary{Balance: platform.Msats(math.MaxInt64), AvailableBalance: platform.Sats(-1)}
+	if _, err := overflowing.ReservedBalance(); !errors.Is(err, platform.ErrAmountOverflow) {
+		t.Errorf("Incorrect ReservedBalance Error: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"balance": true}`), &summary); err == nil {
+		t.Error("decoded a boolean amount")
+	}
+
+	// JSON numbers may have an exponent, as long as they are exact to the msat
+	for s, msat := range map[string]int64{"2.1e3": 2100000, "21E+2": 2100000, "1e-3": 1, "0e0": 0} {
+		var a platform.Amount
+		if err := json.Unmarshal([]byte(s), &a); err != nil || a.Msats() != msat {
+			t.Errorf("%s: Incorrect Amount: %s, %v", s, a, err)
+		}
+	}
+	for _, s := range []string{"1.5e-3", "1e20", "1e"} {
+		var a platform.Amount
+		if err := json.Unmarshal([]byte(s), &a); err == nil {
+			t.Errorf("%s: decoded as %s", s, a)
+		}
+	}
+}
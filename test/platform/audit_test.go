@@ -0,0 +1,182 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// auditResponse answers every call of the audit tests
+var auditResponse = []byte(`{"id": "wd_1", "amount": 100, "url": "https://example.com/hook", "secret": "whsec_1", "enabled": true}`)
+
+// TestAuditLog checks the records written for mutating calls
+func TestAuditLog(t *testing.T) {
+	tps := newServer(http.StatusOK, auditResponse)
+	defer tps.Close()
+
+	var buf bytes.Buffer
+	tpc := newClient(t, tps.URL, platform.WithAuditLog(platform.NewAuditLog(&buf, "destination")))
+	ctx := platform.ContextWithAuditActor(context.Background(), "payout-job")
+	if _, err := tpc.InitiateWithdrawalContext(ctx, platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := tpc.SubscribeToWebhook("https://example.com/hook"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Incorrect Records: %d\n%s", len(lines), buf.String())
+	}
+	var withdrawal, webhook platform.AuditRecord
+	if err := json.Unmarshal([]byte(lines[0]), &withdrawal); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &webhook); err != nil {
+		t.Fatal(err.Error())
+	}
+	if withdrawal.Seq != 1 || withdrawal.Endpoint != platform.EndpointInitiateWithdrawal || withdrawal.Account != "acc_test" ||
+		withdrawal.Actor != "payout-job" || withdrawal.IdempotencyKey == "" || withdrawal.StatusCode != http.StatusOK {
+		t.Errorf("Incorrect Withdrawal Record: %s", lines[0])
+	}
+	if !strings.Contains(string(withdrawal.Request), `"amount":100`) || !strings.Contains(string(withdrawal.Request), `"destination":"[REDACTED]"`) {
+		t.Errorf("Incorrect Withdrawal Request: %s", withdrawal.Request)
+	}
+	if webhook.Seq != 2 || webhook.PrevHash != withdrawal.Hash || strings.Contains(lines[1], "whsec_1") {
+		t.Errorf("Incorrect Webhook Record: %s", lines[1])
+	}
+
+	if n, err := platform.VerifyAuditLog(strings.NewReader(buf.String())); err != nil || n != 2 {
+		t.Errorf("Verification Failed: %d, %v", n, err)
+	}
+}
+
+// failingWriter fails every write after the first ok ones
+type failingWriter struct {
+	ok  int
+	buf bytes.Buffer
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	if fw.ok == 0 {
+		return 0, errDiskFull
+	}
+	fw.ok--
+	return fw.buf.Write(p)
+}
+
+// TestAuditLogFail_Writer checks that mutating calls are refused once a record could not be written
+func TestAuditLogFail_Writer(t *testing.T) {
+	var gets, posts int32
+	tps := newCountingServer(0, &gets, &posts)
+	defer tps.Close()
+
+	al := platform.NewAuditLog(&failingWriter{ok: 1})
+	tpc := newClient(t, tps.URL, platform.WithAuditLog(al))
+	for i := 0; i < 2; i++ {
+		// the second withdrawal is made, but its record fails
+		if _, err := tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10)); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if err := al.Err(); !errors.Is(err, errDiskFull) {
+		t.Fatalf("Incorrect Err: %v", err)
+	}
+
+	if _, err := tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10)); !errors.Is(err, errDiskFull) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+	if n := atomic.LoadInt32(&posts); n != 2 {
+		t.Errorf("Incorrect Mutating Requests: %d", n)
+	}
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Errorf("read refused: %s", err.Error())
+	}
+}
+
+// TestAuditLogFail_Tampered checks that edits, gaps and reordering are detected
+func TestAuditLogFail_Tampered(t *testing.T) {
+	tps := newServer(http.StatusOK, auditResponse)
+	defer tps.Close()
+
+	var buf bytes.Buffer
+	tpc := newClient(t, tps.URL, platform.WithAuditLog(platform.NewAuditLog(&buf)))
+	for i := 0; i < 3; i++ {
+		if _, err := tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10)); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	tampered := map[string][]string{
+		"edited":    {lines[0], strings.Replace(lines[1], `"amount":100`, `"amount":1000`, 1), lines[2]},
+		"removed":   {lines[0], lines[2]},
+		"reordered": {lines[0], lines[2], lines[1]},
+		"truncated": {lines[1], lines[2]},
+	}
+	for name, records := range tampered {
+		_, err := platform.VerifyAuditLog(strings.NewReader(strings.Join(records, "\n")))
+		var chainErr *platform.AuditChainError
+		if !errors.Is(err, platform.ErrAuditChain) || !errors.As(err, &chainErr) {
+			t.Errorf("%s log: Incorrect Error: %v", name, err)
+		} else if want := map[string]int{"edited": 2, "removed": 2, "reordered": 2, "truncated": 1}[name]; chainErr.Line != want {
+			t.Errorf("%s log: Incorrect Line: %d", name, chainErr.Line)
+		}
+	}
+}
+
+// TestOpenAuditLog checks that a reopened log continues its chain
+func TestOpenAuditLog(t *testing.T) {
+	tps := newServer(http.StatusOK, auditResponse)
+	defer tps.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for i := 0; i < 2; i++ {
+		al, err := platform.OpenAuditLog(path)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		tpc := newClient(t, tps.URL, platform.WithAuditLog(al))
+		if _, err := tpc.CreateDepositInvoice(platform.Sats(100), "label", platform.LN); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := al.Err(); err != nil {
+			t.Fatal(err.Error())
+		}
+		if err := al.Close(); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer f.Close()
+	if n, err := platform.VerifyAuditLog(f); err != nil || n != 2 {
+		t.Errorf("Verification Failed: %d, %v", n, err)
+	}
+
+	// a tampered log is not appended to
+	b, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, bytes.Replace(b, []byte(`"seq":1`), []byte(`"seq":7`), 1), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := platform.OpenAuditLog(path); !errors.Is(err, platform.ErrAuditChain) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+}
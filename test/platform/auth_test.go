@@ -0,0 +1,184 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newAuthServer responds to every request with the Authorization header it received
+func newAuthServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"id": %q, "balance": 0}`, r.Header.Get("Authorization"))
+	}))
+}
+
+// newTokenServer issues tokens numbered by hits that expire after expiresIn seconds
+func newTokenServer(t *testing.T, expiresIn int, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if err := r.ParseForm(); err != nil {
+			t.Error(err.Error())
+		}
+		if r.Method != http.MethodPost || r.PostForm.Get("grant_type") != "client_credentials" {
+			t.Errorf("Incorrect Token Request: %s %v", r.Method, r.PostForm)
+		}
+		if id != "client" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "invalid_client", "error_description": "unknown client"}`))
+			return
+		}
+		n := atomic.AddInt32(hits, 1)
+		time.Sleep(10 * time.Millisecond)
+		_, _ = fmt.Fprintf(w, `{"access_token": "token%d", "token_type": "Bearer", "expires_in": %d, "scope": %q}`, n, expiresIn, r.PostForm.Get("scope"))
+	}))
+}
+
+// authHeader returns the Authorization header received by a server created by newAuthServer
+func authHeader(t *testing.T, tpc *platform.PlatformClient) string {
+	acct, err := tpc.AccountBalance()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return acct.Id
+}
+
+// TestStaticAuthenticators checks the basic and bearer schemes
+func TestStaticAuthenticators(t *testing.T) {
+	tps := newAuthServer()
+	defer tps.Close()
+
+	if auth := authHeader(t, newClient(t, tps.URL)); auth != "basic YXBpc2VjcmV0OmFwaXNlY3JldA==" {
+		t.Errorf("Incorrect Basic Authorization: %s", auth)
+	}
+	tpc := newClient(t, tps.URL, platform.WithAuthenticator(platform.BearerToken("tok")))
+	if auth := authHeader(t, tpc); auth != "Bearer tok" {
+		t.Errorf("Incorrect Bearer Authorization: %s", auth)
+	}
+
+	tpc, err := platform.NewPlatformClient(
+		platform.WithBaseURL(tps.URL),
+		platform.WithAccountID("acc_test"),
+		platform.WithAuthenticator(platform.BearerToken("tok")),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if auth := authHeader(t, tpc); auth != "Bearer tok" {
+		t.Errorf("Incorrect Bearer Authorization: %s", auth)
+	}
+
+	if _, err := platform.NewPlatformClient(platform.WithBaseURL(tps.URL), platform.WithAccountID("acc_test")); err == nil {
+		t.Error("accepted client without credentials")
+	}
+}
+
+// TestAuthenticatorFail checks that a failing Authenticator fails the call before it is sent
+func TestAuthenticatorFail(t *testing.T) {
+	var hits int32
+	tps := newFlakyServer(0, http.StatusOK, []byte(`{}`), &hits)
+	defer tps.Close()
+
+	errAuth := errors.New("no credentials")
+	tpc := newClient(t, tps.URL, platform.WithAuthenticator(platform.AuthenticatorFunc(
+		func(ctx context.Context, req *http.Request) error {
+			return errAuth
+		})))
+	if _, err := tpc.AccountBalance(); !errors.Is(err, errAuth) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 0 {
+		t.Errorf("Incorrect Requests: %d", n)
+	}
+}
+
+// TestOAuth2Authenticator checks that tokens are cached, shared by concurrent calls and refreshed
+func TestOAuth2Authenticator(t *testing.T) {
+	var hits int32
+	tts := newTokenServer(t, 2, &hits)
+	defer tts.Close()
+	tps := newAuthServer()
+	defer tps.Close()
+
+	auth, err := platform.NewOAuth2Authenticator(platform.OAuth2Config{
+		TokenURL:     tts.URL,
+		ClientID:     "client",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"balance", "withdraw"},
+		ExpiryDelta:  time.Second,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tpc := newClient(t, tps.URL, platform.WithAuthenticator(auth))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			acct, err := tpc.AccountBalance()
+			if err != nil {
+				t.Error(err.Error())
+			} else if acct.Id != "Bearer token1" {
+				t.Errorf("Incorrect Authorization: %s", acct.Id)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("Incorrect Token Requests: %d", n)
+	}
+
+	auth.Invalidate()
+	if got := authHeader(t, tpc); got != "Bearer token2" {
+		t.Errorf("Incorrect Authorization after Invalidate: %s", got)
+	}
+
+	// the token is refreshed ExpiryDelta before it expires
+	time.Sleep(1100 * time.Millisecond)
+	if got := authHeader(t, tpc); got != "Bearer token3" {
+		t.Errorf("Incorrect Authorization after expiry: %s", got)
+	}
+}
+
+// TestOAuth2AuthenticatorFail checks rejected client credentials and invalid configs
+func TestOAuth2AuthenticatorFail(t *testing.T) {
+	var hits int32
+	tts := newTokenServer(t, 3600, &hits)
+	defer tts.Close()
+
+	auth, err := platform.NewOAuth2Authenticator(platform.OAuth2Config{
+		TokenURL:     tts.URL,
+		ClientID:     "client",
+		ClientSecret: "wrong",
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = auth.Token(context.Background())
+	var tokenErr *platform.TokenError
+	if !errors.As(err, &tokenErr) || tokenErr.Code != "invalid_client" || !errors.Is(err, platform.ErrUnauthorized) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+
+	configs := []platform.OAuth2Config{
+		{TokenURL: "", ClientID: "client"},
+		{TokenURL: "ftp://auth.example.com/token", ClientID: "client"},
+		{TokenURL: tts.URL},
+		{TokenURL: tts.URL, ClientID: "client", ExpiryDelta: -time.Second},
+	}
+	for _, config := range configs {
+		if _, err := platform.NewOAuth2Authenticator(config); err == nil {
+			t.Errorf("accepted invalid config %+v", config)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package platform
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// TestCircuitBreaker checks the closed -> open -> half-open -> closed cycle
+func TestCircuitBreaker(t *testing.T) {
+	var failing int32 = 1
+	var hits int32
+	tps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer tps.Close()
+
+	var transitions []string
+	settings := platform.DefaultCircuitBreakerSettings()
+	settings.MinRequests = 2
+	settings.CoolDown = 50 * time.Millisecond
+	settings.OnStateChange = func(from, to platform.CircuitState) {
+		transitions = append(transitions, from.String()+">"+to.String())
+	}
+	cb, err := platform.NewCircuitBreaker(settings)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tpc := newClient(t, tps.URL, platform.WithCircuitBreaker(cb))
+
+	for i := 0; i < 2; i++ {
+		if _, err := tpc.AccountBalance(); err == nil {
+			t.Fatal("failed to fail")
+		}
+	}
+	if cb.State() != platform.StateOpen {
+		t.Fatalf("Incorrect State: %s", cb.State())
+	}
+	if _, err := tpc.AccountBalance(); !errors.Is(err, platform.ErrCircuitOpen) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("open circuit sent a request: %d", n)
+	}
+
+	atomic.StoreInt32(&failing, 0)
+	time.Sleep(60 * time.Millisecond)
+	if cb.State() != platform.StateHalfOpen {
+		t.Fatalf("Incorrect State: %s", cb.State())
+	}
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if cb.State() != platform.StateClosed {
+		t.Errorf("Incorrect State: %s", cb.State())
+	}
+
+	want := []string{"closed>open", "open>half-open", "half-open>closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("Incorrect Transitions: %v", transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("Incorrect Transitions: %v", transitions)
+		}
+	}
+}
+
+// TestCircuitBreaker_ClientErrors checks that 4xx responses do not open the circuit
+func TestCircuitBreaker_ClientErrors(t *testing.T) {
+	tps := newServer(http.StatusNotFound, []byte(""))
+	defer tps.Close()
+
+	settings := platform.DefaultCircuitBreakerSettings()
+	settings.MinRequests = 1
+	cb, _ := platform.NewCircuitBreaker(settings)
+	tpc := newClient(t, tps.URL, platform.WithCircuitBreaker(cb))
+	for i := 0; i < 3; i++ {
+		if _, err := tpc.GetWithdrawal("wd_test"); !errors.Is(err, platform.ErrNotFound) {
+			t.Errorf("Incorrect Error: %v", err)
+		}
+	}
+	if cb.State() != platform.StateClosed {
+		t.Errorf("Incorrect State: %s", cb.State())
+	}
+}
@@ -0,0 +1,72 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// TestCallTimeout checks that a per-call timeout cancels a slow call without affecting the client
+func TestCallTimeout(t *testing.T) {
+	tps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Slow") != "" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+		_, _ = w.Write([]byte(`{"deposits": []}`))
+	}))
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL)
+	_, err := tpc.GetDepositsContext(context.Background(), 10, 0,
+		platform.WithCallTimeout(50*time.Millisecond),
+		platform.WithHeader("X-Slow", "1"),
+	)
+	if err == nil {
+		t.Fatal("failed to fail")
+	}
+	if _, err := tpc.GetDepositsContext(context.Background(), 10, 0); err != nil {
+		t.Errorf("client unusable after call timeout: %s", err.Error())
+	}
+}
+
+// TestContextCanceled checks that a canceled Context is returned as the call's error
+func TestContextCanceled(t *testing.T) {
+	tps := newServer(http.StatusOK, []byte(`{}`))
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tpc.AccountBalanceContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Errorf("default Context affected by canceled call: %s", err.Error())
+	}
+}
+
+// TestWithHeader checks that extra headers reach the server
+func TestWithHeader(t *testing.T) {
+	var got string
+	tps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Request-Source")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL)
+	if _, err := tpc.GetSubscribedWebhookContext(context.Background(), platform.WithHeader("X-Request-Source", "cron")); err != nil {
+		t.Fatal(err.Error())
+	}
+	if got != "cron" {
+		t.Errorf("Incorrect Header: %q", got)
+	}
+}
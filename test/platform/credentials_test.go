@@ -0,0 +1,210 @@
+package platform
+
+import (
+	"context"
+	b64 "encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newSecretServer accepts only requests authenticated with the API secret held by secret, counting every request
+func newSecretServer(secret *atomic.Value, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		s := secret.Load().(string)
+		if r.Header.Get("Authorization") != "basic "+b64.StdEncoding.EncodeToString([]byte(s+":"+s)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "acc_test", "balance": 2100}`))
+	}))
+}
+
+// newProviderClient creates a client for acc_test authenticated by provider
+func newProviderClient(t *testing.T, baseUrl string, provider platform.CredentialProvider) *platform.PlatformClient {
+	tpc, err := platform.NewPlatformClient(
+		platform.WithBaseURL(baseUrl),
+		platform.WithAccountID("acc_test"),
+		platform.WithCredentialProvider(provider),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return tpc
+}
+
+// TestFileCredentialProvider checks that a rotated secret file is picked up by polling, and on a 401
+func TestFileCredentialProvider(t *testing.T) {
+	var secret atomic.Value
+	secret.Store("secret1")
+	var hits int32
+	tps := newSecretServer(&secret, &hits)
+	defer tps.Close()
+
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("secret1\n"), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+	provider, err := platform.NewFileCredentialProvider(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tpc := newProviderClient(t, tps.URL, provider)
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	secret.Store("secret22")
+	if err := os.WriteFile(path, []byte("secret22\n"), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+	time.Sleep(20 * time.Millisecond)
+	atomic.StoreInt32(&hits, 0)
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("Incorrect Requests after polling: %d", n)
+	}
+
+	// a file that changed before it was polled is reloaded after a 401
+	provider, err = platform.NewFileCredentialProvider(path, time.Hour)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tpc = newProviderClient(t, tps.URL, provider)
+	secret.Store("secret333")
+	if err := os.WriteFile(path, []byte("secret333\n"), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+	atomic.StoreInt32(&hits, 0)
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("Incorrect Requests after 401: %d", n)
+	}
+}
+
+// TestStaticCredentialProvider checks in place rotation, and that a mutating call is retried once on a 401
+func TestStaticCredentialProvider(t *testing.T) {
+	var secret atomic.Value
+	secret.Store("old")
+	var hits int32
+	tps := newSecretServer(&secret, &hits)
+	defer tps.Close()
+
+	provider, err := platform.NewStaticCredentialProvider("old")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tpc := newProviderClient(t, tps.URL, provider)
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	secret.Store("new")
+	if err := provider.Rotate("new"); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// a refresh that does not change the secret is retried once only
+	secret.Store("newer")
+	atomic.StoreInt32(&hits, 0)
+	_, err = tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10))
+	if !errors.Is(err, platform.ErrUnauthorized) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("Incorrect Requests: %d", n)
+	}
+	if err := provider.Rotate(" "); err == nil {
+		t.Error("accepted empty secret")
+	}
+}
+
+// TestUnauthorizedNotRetried checks that a 401 is not retried when the credentials cannot be refreshed
+func TestUnauthorizedNotRetried(t *testing.T) {
+	var secret atomic.Value
+	secret.Store("other")
+	var hits int32
+	tps := newSecretServer(&secret, &hits)
+	defer tps.Close()
+
+	if _, err := newClient(t, tps.URL).AccountBalance(); !errors.Is(err, platform.ErrUnauthorized) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("Incorrect Requests: %d", n)
+	}
+}
+
+// TestEnvCredentialProvider checks that the variable is read on every call
+func TestEnvCredentialProvider(t *testing.T) {
+	var secret atomic.Value
+	secret.Store("env1")
+	var hits int32
+	tps := newSecretServer(&secret, &hits)
+	defer tps.Close()
+
+	t.Setenv("TEST_RIVER_API_SECRET", "env1")
+	provider, err := platform.NewEnvCredentialProvider("TEST_RIVER_API_SECRET")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tpc := newProviderClient(t, tps.URL, provider)
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	secret.Store("env2")
+	t.Setenv("TEST_RIVER_API_SECRET", "env2")
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := platform.NewEnvCredentialProvider("TEST_RIVER_UNSET_SECRET"); err == nil {
+		t.Error("accepted unset variable")
+	}
+}
+
+// TestCommandCredentialProvider checks the credential helper protocol
+func TestCommandCredentialProvider(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	var secret atomic.Value
+	secret.Store("helper-acc_test")
+	var hits int32
+	tps := newSecretServer(&secret, &hits)
+	defer tps.Close()
+
+	// the helper answers with a secret derived from the username it is given
+	script := `while read line; do case "$line" in username=*) user="${line#username=}";; esac; done; echo "password=helper-$user"`
+	provider, err := platform.NewCommandCredentialProvider(tps.URL, "acc_test", "/bin/sh", "-c", script)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tpc := newProviderClient(t, tps.URL, provider)
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	provider, err = platform.NewCommandCredentialProvider(tps.URL, "acc_test", "/bin/sh", "-c", "echo denied >&2; exit 1")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := provider.Credential(context.Background()); err == nil {
+		t.Error("accepted failing command")
+	}
+}
@@ -0,0 +1,94 @@
+package platform
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// TestDecodeError checks that a malformed body is reported with its endpoint and a snippet
+func TestDecodeError(t *testing.T) {
+	tps := newServer(http.StatusOK, []byte(`{"id": "acc_test", "balance": "lots"}`))
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL)
+	_, err := tpc.AccountBalance()
+	var decodeErr *platform.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Incorrect Error: %v", err)
+	}
+	if decodeErr.Endpoint != platform.EndpointAccountBalance || decodeErr.StatusCode != http.StatusOK ||
+		!strings.Contains(decodeErr.Snippet, `"lots"`) {
+		t.Errorf("Incorrect DecodeError: %+v", decodeErr)
+	}
+}
+
+// TestMaxResponseBytes checks that bodies larger than the limit are rejected
+func TestMaxResponseBytes(t *testing.T) {
+	tps := newServer(http.StatusOK, []byte(`{"id": "`+strings.Repeat("a", 100)+`"}`))
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithMaxResponseBytes(64))
+	if _, err := tpc.AccountBalance(); !errors.Is(err, platform.ErrResponseTooLarge) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+}
+
+// TestSchemaDrift checks that unknown fields are reported, and rejected in strict mode
+func TestSchemaDrift(t *testing.T) {
+	tps := newServer(http.StatusOK, []byte(`{"id": "wd_test", "state": "PENDING", "network_fee": 3, "route": {"hops": 2}}`))
+	defer tps.Close()
+
+	var drifts []platform.SchemaDrift
+	onDrift := platform.WithSchemaDriftHandler(func(d platform.SchemaDrift) {
+		drifts = append(drifts, d)
+	})
+
+	lenient := newClient(t, tps.URL, onDrift)
+	withdrawal, err := lenient.GetWithdrawal("wd_test")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if withdrawal.Id != "wd_test" || withdrawal.State != "PENDING" {
+		t.Errorf("Incorrect Withdrawal: %+v", withdrawal)
+	}
+
+	strict := newClient(t, tps.URL, onDrift, platform.WithStrictDecoding())
+	_, err = strict.GetWithdrawal("wd_test")
+	var decodeErr *platform.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+	var unknownErr *platform.UnknownFieldsError
+	if !errors.As(err, &unknownErr) || len(unknownErr.Fields) != 2 || unknownErr.Fields[0] != "network_fee" || unknownErr.Fields[1] != "route" {
+		t.Errorf("Incorrect Unknown Fields: %v", err)
+	}
+
+	if len(drifts) != 4 || drifts[0].Field != "network_fee" || drifts[1].Field != "route" || drifts[0].Endpoint != platform.EndpointGetWithdrawal {
+		t.Errorf("Incorrect Drifts: %+v", drifts)
+	}
+}
+
+// TestSchemaDrift_Nested checks that unknown fields of nested objects are reported once, with their path
+func TestSchemaDrift_Nested(t *testing.T) {
+	tps := newServer(http.StatusOK, []byte(`{"deposits": [{"id": "dep_1", "fee": 1}, {"id": "dep_2", "fee": 2}], "cursor": "x"}`))
+	defer tps.Close()
+
+	var drifts []platform.SchemaDrift
+	tpc := newClient(t, tps.URL, platform.WithSchemaDriftHandler(func(d platform.SchemaDrift) {
+		drifts = append(drifts, d)
+	}))
+	list, err := tpc.GetDeposits(10, 0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(list.Deposits) != 2 {
+		t.Errorf("Incorrect Deposits: %+v", list)
+	}
+	if len(drifts) != 2 || drifts[0].Field != "cursor" || drifts[1].Field != "deposits.fee" {
+		t.Errorf("Incorrect Drifts: %+v", drifts)
+	}
+}
@@ -0,0 +1,129 @@
+package platform
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// TestDryRun checks that mutating calls are simulated while reads reach the API
+func TestDryRun(t *testing.T) {
+	var gets, posts int32
+	tps := newCountingServer(0, &gets, &posts)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithDryRun())
+	if !tpc.DryRun() {
+		t.Error("client not in dry run")
+	}
+
+	withdrawal, err := tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !withdrawal.Simulated || withdrawal.Amount != platform.Sats(100) || withdrawal.Details.Invoice != "lnbc1" ||
+		!strings.HasPrefix(withdrawal.Id, "simulated_") || withdrawal.IdempotencyKey == "" {
+		t.Errorf("Incorrect Withdrawal: %+v", withdrawal)
+	}
+
+	invoice, err := tpc.CreateDepositInvoice(platform.Sats(100), "label", platform.LN)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !invoice.Simulated || invoice.Invoice != "" || invoice.Network != platform.LN {
+		t.Errorf("Incorrect Deposit Invoice: %+v", invoice)
+	}
+	// Platform API timestamps are in milliseconds
+	if created := time.Unix(0, int64(invoice.Timestamp)*int64(time.Millisecond)); time.Since(created) > time.Minute || time.Since(created) < 0 {
+		t.Errorf("Incorrect Deposit Invoice Timestamp: %d", invoice.Timestamp)
+	}
+
+	webhook, err := tpc.SubscribeToWebhook("https://example.com/hook")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !webhook.Simulated || !webhook.Enabled || webhook.Url != "https://example.com/hook" {
+		t.Errorf("Incorrect Webhook: %+v", webhook)
+	}
+	if err := tpc.UnsubscribeWebhook(); !errors.Is(err, platform.ErrDryRun) {
+		t.Errorf("Incorrect UnsubscribeWebhook Error: %v", err)
+	}
+	if tpc.DeleteWebhook() {
+		t.Error("DeleteWebhook reported a deletion")
+	}
+
+	acct, err := tpc.AccountBalance()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if acct.Balance != platform.Sats(2100) {
+		t.Errorf("Incorrect Balance: %s", acct.Balance)
+	}
+	if n := atomic.LoadInt32(&posts); n != 0 {
+		t.Errorf("Incorrect Mutating Requests: %d", n)
+	}
+	if n := atomic.LoadInt32(&gets); n != 1 {
+		t.Errorf("Incorrect Read Requests: %d", n)
+	}
+}
+
+// TestDryRunFail_Validation checks that dry run calls validate their inputs
+func TestDryRunFail_Validation(t *testing.T) {
+	var gets, posts int32
+	tps := newCountingServer(0, &gets, &posts)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithDryRun())
+	withdrawals := []func() (platform.Withdrawal, error){
+		func() (platform.Withdrawal, error) {
+			return tpc.InitiateWithdrawal(platform.Sats(0), "lnbc1", platform.BTC, platform.LN, platform.Sats(10))
+		},
+		func() (platform.Withdrawal, error) {
+			return tpc.InitiateWithdrawal(platform.Sats(100), "bc1qaddress", platform.BTC, platform.LN, platform.Sats(10))
+		},
+		func() (platform.Withdrawal, error) {
+			return tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", "USD", platform.LN, platform.Sats(10))
+		},
+		func() (platform.Withdrawal, error) {
+			return tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, "ONCHAIN", platform.Sats(10))
+		},
+		func() (platform.Withdrawal, error) {
+			return tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(-1))
+		},
+	}
+	for i, withdraw := range withdrawals {
+		if _, err := withdraw(); !errors.Is(err, platform.ErrInvalidRequest) {
+			t.Errorf("Incorrect Error for withdrawal %d: %v", i, err)
+		}
+	}
+	if _, err := tpc.CreateDepositInvoice(platform.Sats(100), "label", "ONCHAIN"); !errors.Is(err, platform.ErrInvalidRequest) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+	if _, err := tpc.SubscribeToWebhook("example.com/hook"); !errors.Is(err, platform.ErrInvalidRequest) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+	if n := atomic.LoadInt32(&posts); n != 0 {
+		t.Errorf("Incorrect Mutating Requests: %d", n)
+	}
+}
+
+// TestUnsubscribeWebhook checks that the error of a failed deletion is returned
+func TestUnsubscribeWebhook(t *testing.T) {
+	tps := newServer(http.StatusOK, []byte(`{}`))
+	defer tps.Close()
+	if err := newClient(t, tps.URL).UnsubscribeWebhook(); err != nil {
+		t.Error(err.Error())
+	}
+
+	tps = newServer(http.StatusNotFound, []byte(`{"message": "no webhook"}`))
+	defer tps.Close()
+	var apiErr *platform.APIError
+	if err := newClient(t, tps.URL).UnsubscribeWebhook(); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newErrorServer responds to every request with status, a request id and body
+func newErrorServer(status int, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Request-Id", "req_123")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+// TestAPIError_JSONBody checks that the error code, message and request id are parsed
+func TestAPIError_JSONBody(t *testing.T) {
+	tps := newErrorServer(http.StatusBadRequest, `{"error": {"code": "insufficient_funds", "message": "balance too low"}}`)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL)
+	_, err := tpc.InitiateWithdrawal(platform.Sats(2100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10))
+
+	var apiErr *platform.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Incorrect Error: %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != "insufficient_funds" ||
+		apiErr.Message != "balance too low" || apiErr.RequestID != "req_123" {
+		t.Errorf("Incorrect APIError: %+v", apiErr)
+	}
+	if !errors.Is(err, platform.ErrInsufficientFunds) || errors.Is(err, platform.ErrInvoiceExpired) {
+		t.Errorf("Incorrect Sentinel: %v", err)
+	}
+	if platform.IsRetryable(err) {
+		t.Error("insufficient funds is not retryable")
+	}
+}
+
+// TestAPIError_Sentinels checks the sentinel errors matched by status code
+func TestAPIError_Sentinels(t *testing.T) {
+	cases := map[int]error{
+		http.StatusUnauthorized:    platform.ErrUnauthorized,
+		http.StatusForbidden:       platform.ErrUnauthorized,
+		http.StatusNotFound:        platform.ErrNotFound,
+		http.StatusTooManyRequests: platform.ErrRateLimited,
+	}
+	for status, sentinel := range cases {
+		tps := newErrorServer(status, "")
+		tpc := newClient(t, tps.URL)
+		_, err := tpc.GetWithdrawal("wd_test")
+		tps.Close()
+		if !errors.Is(err, sentinel) {
+			t.Errorf("status %d: %v is not %v", status, err, sentinel)
+		}
+	}
+}
+
+// TestIsRetryable checks which errors are reported as retryable
+func TestIsRetryable(t *testing.T) {
+	if !platform.IsRetryable(&platform.APIError{StatusCode: http.StatusServiceUnavailable}) {
+		t.Error("503 should be retryable")
+	}
+	if platform.IsRetryable(&platform.APIError{StatusCode: http.StatusInternalServerError}) {
+		t.Error("500 should not be retryable")
+	}
+	if platform.IsRetryable(context.Canceled) || platform.IsRetryable(nil) {
+		t.Error("canceled context should not be retryable")
+	}
+
+	tpc := newClient(t, "http://127.0.0.1:1")
+	if _, err := tpc.AccountBalance(); !platform.IsRetryable(err) {
+		t.Errorf("connection error should be retryable: %v", err)
+	}
+}
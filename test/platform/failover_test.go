@@ -0,0 +1,181 @@
+package platform
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newSwitchServer responds 503 while down is set, and counts the requests it receives by method
+func newSwitchServer(down *int32, reads, writes *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(reads, 1)
+		} else {
+			atomic.AddInt32(writes, 1)
+		}
+		if atomic.LoadInt32(down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "acc_test", "balance": 2100}`))
+	}))
+}
+
+// changeLog records EndpointChanges
+type changeLog struct {
+	mu      sync.Mutex
+	changes []platform.EndpointChange
+}
+
+func (cl *changeLog) add(c platform.EndpointChange) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.changes = append(cl.changes, c)
+}
+
+func (cl *changeLog) get() []platform.EndpointChange {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return append([]platform.EndpointChange(nil), cl.changes...)
+}
+
+// TestFailover checks read failover, sticky writes and recovery of the preferred base URL
+func TestFailover(t *testing.T) {
+	var primaryDown, primaryReads, primaryWrites int32
+	primary := newSwitchServer(&primaryDown, &primaryReads, &primaryWrites)
+	defer primary.Close()
+	var secondaryDown, secondaryReads, secondaryWrites int32
+	secondary := newSwitchServer(&secondaryDown, &secondaryReads, &secondaryWrites)
+	defer secondary.Close()
+
+	var log changeLog
+	tpc, err := platform.NewPlatformClient(
+		platform.WithBaseURLs(primary.URL, secondary.URL),
+		platform.WithAccount("acc_test", "apisecret"),
+		platform.WithFailover(platform.FailoverSettings{
+			ProbeInterval: 10 * time.Millisecond,
+			ProbeTimeout:  time.Second,
+			OnChange:      log.add,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if tpc.BaseURL != primary.URL {
+		t.Errorf("Incorrect BaseURL: %s", tpc.BaseURL)
+	}
+
+	// reads fail over within the call
+	atomic.StoreInt32(&primaryDown, 1)
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if atomic.LoadInt32(&primaryReads) != 1 || atomic.LoadInt32(&secondaryReads) != 1 {
+		t.Errorf("Incorrect Reads: %d, %d", primaryReads, secondaryReads)
+	}
+	changes := log.get()
+	if len(changes) != 1 || changes[0].Writes || changes[0].From != primary.URL || changes[0].To != secondary.URL || changes[0].Err == nil {
+		t.Errorf("Incorrect Changes: %+v", changes)
+	}
+	if status := tpc.BaseURLs(); len(status) != 2 || status[0].Healthy || !status[1].Healthy {
+		t.Errorf("Incorrect Status: %+v", status)
+	}
+
+	// writes move once their base URL is unhealthy, and stay
+	if _, err := tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if atomic.LoadInt32(&primaryWrites) != 0 || atomic.LoadInt32(&secondaryWrites) != 1 {
+		t.Errorf("Incorrect Writes: %d, %d", primaryWrites, secondaryWrites)
+	}
+	changes = log.get()
+	if len(changes) != 2 || !changes[1].Writes || changes[1].To != secondary.URL {
+		t.Errorf("Incorrect Changes: %+v", changes)
+	}
+
+	// reads move back to the preferred base URL once it answers a ping
+	atomic.StoreInt32(&primaryDown, 0)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(log.get()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(15 * time.Millisecond)
+		if _, err := tpc.AccountBalance(); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	changes = log.get()
+	if len(changes) != 3 || changes[2].Writes || changes[2].To != primary.URL || changes[2].Err != nil {
+		t.Fatalf("Incorrect Changes: %+v", changes)
+	}
+	if _, err := tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if atomic.LoadInt32(&primaryWrites) != 0 || atomic.LoadInt32(&secondaryWrites) != 2 {
+		t.Errorf("Incorrect Writes after recovery: %d, %d", primaryWrites, secondaryWrites)
+	}
+}
+
+// TestFailover_Writes checks that a failed write is not sent to another base URL
+func TestFailover_Writes(t *testing.T) {
+	var primaryDown, primaryReads, primaryWrites int32
+	primary := newSwitchServer(&primaryDown, &primaryReads, &primaryWrites)
+	defer primary.Close()
+	var secondaryDown, secondaryReads, secondaryWrites int32
+	secondary := newSwitchServer(&secondaryDown, &secondaryReads, &secondaryWrites)
+	defer secondary.Close()
+
+	tpc, err := platform.NewPlatformClient(
+		platform.WithBaseURLs(primary.URL, secondary.URL),
+		platform.WithAccount("acc_test", "apisecret"),
+		platform.WithRetryPolicy(fastRetryPolicy()),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	atomic.StoreInt32(&primaryDown, 1)
+	if _, err := tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10)); err == nil {
+		t.Error("write succeeded")
+	}
+	if n := atomic.LoadInt32(&primaryWrites); n != int32(fastRetryPolicy().MaxAttempts) {
+		t.Errorf("Incorrect Primary Writes: %d", n)
+	}
+	if n := atomic.LoadInt32(&secondaryWrites); n != 0 {
+		t.Errorf("Incorrect Secondary Writes: %d", n)
+	}
+}
+
+// TestCheckBaseURLs checks that every base URL is pinged
+func TestCheckBaseURLs(t *testing.T) {
+	var primaryDown, primaryReads, primaryWrites int32
+	primary := newSwitchServer(&primaryDown, &primaryReads, &primaryWrites)
+	defer primary.Close()
+	var secondaryDown, secondaryReads, secondaryWrites int32
+	secondary := newSwitchServer(&secondaryDown, &secondaryReads, &secondaryWrites)
+	defer secondary.Close()
+
+	tpc, err := platform.NewPlatformClient(
+		platform.WithBaseURLs(primary.URL, secondary.URL),
+		platform.WithAccount("acc_test", "apisecret"),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	atomic.StoreInt32(&secondaryDown, 1)
+	status := tpc.CheckBaseURLs(context.Background())
+	if len(status) != 2 || !status[0].Healthy || status[1].Healthy || status[1].LastError == nil {
+		t.Errorf("Incorrect Status: %+v", status)
+	}
+	if status := newClient(t, primary.URL).CheckBaseURLs(context.Background()); status != nil {
+		t.Errorf("Incorrect Status of single base URL: %+v", status)
+	}
+
+	if _, err := platform.NewPlatformClient(platform.WithBaseURLs(primary.URL, primary.URL+"/")); err == nil {
+		t.Error("accepted duplicate base URLs")
+	}
+}
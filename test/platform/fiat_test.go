@@ -0,0 +1,224 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// mustRate returns a Rate or fails the test
+func mustRate(t *testing.T, currency, perBTC string, at time.Time) platform.Rate {
+	rate, err := platform.ParseRate(currency, perBTC, at)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return rate
+}
+
+// TestToFiat checks conversion under every rounding mode
+func TestToFiat(t *testing.T) {
+	rate := mustRate(t, "usd", "60000", time.Time{})
+	// 1 sat at 60000 USD/BTC is 0.06 cents, 25 sats 1.5 cents and 75 sats 4.5 cents
+	cases := []struct {
+		amount platform.Amount
+		mode   platform.RoundingMode
+		want   string
+	}{
+		{platform.Sats(25), platform.RoundHalfEven, "0.02 USD"},
+		{platform.Sats(75), platform.RoundHalfEven, "0.04 USD"},
+		{platform.Sats(25), platform.RoundHalfUp, "0.02 USD"},
+		{platform.Sats(75), platform.RoundHalfUp, "0.05 USD"},
+		{platform.Sats(1), platform.RoundDown, "0.00 USD"},
+		{platform.Sats(1), platform.RoundUp, "0.01 USD"},
+		{platform.Sats(-75), platform.RoundHalfUp, "-0.05 USD"},
+		{platform.Sats(-1), platform.RoundDown, "0.00 USD"},
+		{platform.Sats(-1), platform.RoundUp, "-0.01 USD"},
+		{platform.MustParseAmount("1.5 BTC"), platform.RoundDown, "90000.00 USD"},
+	}
+	for _, c := range cases {
+		fiat, err := platform.ToFiat(c.amount, rate, c.mode)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if fiat.String() != c.want {
+			t.Errorf("%s rounded %s: %s, want %s", c.amount, c.mode, fiat, c.want)
+		}
+	}
+
+	yen, err := platform.ToFiat(platform.Sats(1000), mustRate(t, "JPY", "9000000", time.Time{}), platform.RoundHalfEven)
+	if err != nil || yen.String() != "90 JPY" || yen.Minor != 90 {
+		t.Errorf("Incorrect JPY: %s, %v", yen, err)
+	}
+	if _, err := platform.ParseRate("USD", "1/3", time.Time{}); err == nil {
+		t.Error("parsed a fraction")
+	}
+	if _, err := platform.ParseRate("US", "60000", time.Time{}); err == nil {
+		t.Error("parsed an invalid currency")
+	}
+	if s := mustRate(t, "EUR", "61234.250", time.Time{}).PerBTC(); s != "61234.25" {
+		t.Errorf("Incorrect PerBTC: %s", s)
+	}
+}
+
+// TestCSVRateProvider checks that the latest rate at or before a time is used
+func TestCSVRateProvider(t *testing.T) {
+	csv := `time,currency,price
+2024-03-02,USD,62000
+# comment
+2024-03-01T00:00:00Z,USD,61000.50
+1709337600,EUR,57000
+`
+	provider, err := platform.NewCSVRateProvider(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	cases := map[time.Time]string{
+		time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC): "61000.5",
+		time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC):  "62000",
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC):  "62000",
+	}
+	for at, want := range cases {
+		rate, err := provider.Rate(context.Background(), "USD", at)
+		if err != nil || rate.PerBTC() != want {
+			t.Errorf("%s: Incorrect Rate: %s, %v", at, rate, err)
+		}
+	}
+	if _, err := provider.Rate(context.Background(), "USD", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, platform.ErrNoRate) {
+		t.Errorf("Incorrect Error before history: %v", err)
+	}
+	if _, err := provider.Rate(context.Background(), "GBP", time.Now()); !errors.Is(err, platform.ErrNoRate) {
+		t.Errorf("Incorrect Error of unknown currency: %v", err)
+	}
+
+	if _, err := platform.NewCSVRateProvider(strings.NewReader("2024-03-01,USD,61000\nyesterday,USD,1\n")); err == nil {
+		t.Error("accepted an invalid time")
+	}
+}
+
+// TestHTTPRateProvider checks fetching, parsing and caching of rates
+func TestHTTPRateProvider(t *testing.T) {
+	var hits int32
+	tps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path != "/prices/BTC-USD/spot" || r.URL.Query().Get("date") != "2024-03-01" || r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"amount": "61000.50", "currency": "USD"}}`))
+	}))
+	defer tps.Close()
+
+	provider, err := platform.NewHTTPRateProvider(platform.HTTPRateConfig{
+		URL:    tps.URL + "/prices/BTC-{currency}/spot?date={date}",
+		Field:  "data.amount",
+		Header: http.Header{"X-Api-Key": []string{"key"}},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// a daily rate is stamped with the start of its day, whatever time of the day is asked for
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, hour := range []time.Duration{15, 9} {
+		rate, err := provider.Rate(context.Background(), "usd", day.Add(hour*time.Hour))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if rate.PerBTC() != "61000.5" || rate.Currency != "USD" || !rate.Time.Equal(day) {
+			t.Errorf("Incorrect Rate: %s at %s", rate, rate.Time)
+		}
+	}
+	at := day.Add(15 * time.Hour)
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("Incorrect Hits: %d", n)
+	}
+	if _, err := provider.Rate(context.Background(), "EUR", at); !errors.Is(err, platform.ErrNoRate) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+
+	if _, err := platform.NewHTTPRateProvider(platform.HTTPRateConfig{URL: tps.URL, Field: "price"}); err == nil {
+		t.Error("accepted a URL without {currency}")
+	}
+}
+
+// TestHTTPRateProvider_Spot checks that an endpoint of current rates has no rate for other times
+func TestHTTPRateProvider_Spot(t *testing.T) {
+	tps := newServer(http.StatusOK, []byte(`{"price": 61000}`))
+	defer tps.Close()
+
+	provider, err := platform.NewHTTPRateProvider(platform.HTTPRateConfig{
+		URL:      tps.URL + "/spot/{currency}",
+		Field:    "price",
+		CacheTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	rate, err := provider.Rate(context.Background(), "USD", time.Now().Add(-30*time.Second))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if rate.PerBTC() != "61000" || time.Since(rate.Time) > time.Second {
+		t.Errorf("Incorrect Rate: %s", rate)
+	}
+	for _, at := range []time.Time{time.Now().Add(-time.Hour), time.Now().Add(time.Hour)} {
+		if _, err := provider.Rate(context.Background(), "USD", at); !errors.Is(err, platform.ErrNoRate) {
+			t.Errorf("Incorrect Error at %s: %v", at, err)
+		}
+	}
+}
+
+// TestFiatConverter checks the valuation of balances, deposits and withdrawals
+func TestFiatConverter(t *testing.T) {
+	provider, err := platform.NewCSVRateProvider(strings.NewReader("2021-10-23,USD,60000\n2021-10-24,USD,61000\n"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	fc := &platform.FiatConverter{Provider: provider, Currency: "USD", Rounding: platform.RoundHalfUp}
+
+	summary, err := fc.AccountSummary(context.Background(), platform.AccountSummary{
+		Id:               "acc_satoshi",
+		Balance:          platform.Sats(100075),
+		AvailableBalance: platform.Sats(25),
+	}, time.Date(2021, 10, 23, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// 100075 sats is 60.045 USD and 25 sats 0.015 USD, both rounded up
+	if summary.Balance.String() != "60.05 USD" || summary.AvailableBalance.String() != "0.02 USD" ||
+		summary.ReservedBalance.String() != "60.03 USD" {
+		t.Errorf("Incorrect FiatAccountSummary: %s, %s, %s", summary.Balance, summary.AvailableBalance, summary.ReservedBalance)
+	}
+
+	// 1634975794000 is 2021-10-23T08:36:34Z
+	deposit, err := fc.Deposit(context.Background(), platform.Deposit{Amount: platform.Sats(250000), Timestamp: 1634975794000})
+	if err != nil || deposit.String() != "150.00 USD" {
+		t.Errorf("Incorrect Deposit: %s, %v", deposit, err)
+	}
+	withdrawal, err := fc.Withdrawal(context.Background(), platform.Withdrawal{Amount: platform.Sats(100000)}, time.Date(2021, 10, 25, 0, 0, 0, 0, time.UTC))
+	if err != nil || withdrawal.String() != "61.00 USD" || withdrawal.Rate.PerBTC() != "61000" {
+		t.Errorf("Incorrect Withdrawal: %s, %v", withdrawal, err)
+	}
+}
+
+// TestFiatConverterFail_Overflow checks that a reserved balance that does not fit is an error
+func TestFiatConverterFail_Overflow(t *testing.T) {
+	// a rate at which -1 BTC is exactly the smallest int64 of cents
+	provider, err := platform.NewStaticRateProvider(mustRate(t, "USD", "92233720368547758.08", time.Time{}))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	fc := &platform.FiatConverter{Provider: provider, Currency: "USD"}
+	summary, err := fc.AccountSummary(context.Background(), platform.AccountSummary{
+		AvailableBalance: platform.MustParseAmount("-1 BTC"),
+	}, time.Now())
+	if err == nil {
+		t.Errorf("Incorrect Summary: %+v", summary)
+	}
+}
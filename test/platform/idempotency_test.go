@@ -0,0 +1,132 @@
+package platform
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newKeyServer records the idempotency keys it receives and fails the first failures requests with 503
+func newKeyServer(failures int32, keys *[]string) *httptest.Server {
+	var hits int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*keys = append(*keys, r.Header.Get(platform.IdempotencyKeyHeader))
+		if atomic.AddInt32(&hits, 1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "wd_test"}`))
+	}))
+}
+
+// TestInitiateWithdrawal_GeneratedKey checks that a generated key is sent, reused across retries and returned
+func TestInitiateWithdrawal_GeneratedKey(t *testing.T) {
+	var keys []string
+	tps := newKeyServer(1, &keys)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithRetryPolicy(fastRetryPolicy()))
+	withdrawal, err := tpc.InitiateWithdrawal(platform.Sats(2100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("Incorrect Keys: %v", keys)
+	}
+	if withdrawal.IdempotencyKey != keys[0] {
+		t.Errorf("Incorrect IdempotencyKey: %s", withdrawal.IdempotencyKey)
+	}
+}
+
+// TestInitiateWithdrawalFail_KeyReturned checks that the key is returned with the error of a failed withdrawal
+func TestInitiateWithdrawalFail_KeyReturned(t *testing.T) {
+	var keys []string
+	tps := newKeyServer(1, &keys)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL)
+	withdrawal, err := tpc.InitiateWithdrawal(platform.Sats(2100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10))
+	if err == nil {
+		t.Fatal("failed to fail")
+	}
+	if withdrawal.IdempotencyKey == "" || withdrawal.IdempotencyKey != keys[0] {
+		t.Errorf("Incorrect IdempotencyKey: %q", withdrawal.IdempotencyKey)
+	}
+
+	// repeating the withdrawal with the returned key
+	retried, err := tpc.InitiateWithdrawalContext(context.Background(), platform.Sats(2100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10),
+		platform.WithIdempotencyKey(withdrawal.IdempotencyKey),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if keys[1] != withdrawal.IdempotencyKey || retried.IdempotencyKey != withdrawal.IdempotencyKey {
+		t.Errorf("Incorrect Keys: %v", keys)
+	}
+}
+
+// TestInitiateWithdrawal_CallerOptions checks that generating a key leaves the caller's options untouched
+func TestInitiateWithdrawal_CallerOptions(t *testing.T) {
+	var keys []string
+	tps := newKeyServer(0, &keys)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL)
+	// spare capacity that an append would write the generated key into
+	opts := make([]platform.CallOption, 1, 2)
+	opts[0] = platform.WithHeader("X-Test", "1")
+	for i := 0; i < 2; i++ {
+		if _, err := tpc.InitiateWithdrawalContext(context.Background(), platform.Sats(2100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10), opts...); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if opts[:2][1] != nil {
+		t.Error("caller's options modified")
+	}
+	if len(keys) != 2 || keys[0] == keys[1] {
+		t.Errorf("Incorrect Keys: %v", keys)
+	}
+}
+
+// TestSubmitWithdrawalRequest_Key checks that a WithdrawalRequest keeps its key across submissions
+func TestSubmitWithdrawalRequest_Key(t *testing.T) {
+	var keys []string
+	tps := newKeyServer(0, &keys)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL)
+	wreq, err := platform.NewWithdrawalRequest(platform.Sats(2100), "lnbc1")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := tpc.SubmitWithdrawalRequest(wreq); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if keys[0] != wreq.IdempotencyKey || keys[1] != wreq.IdempotencyKey {
+		t.Errorf("Incorrect Keys: %v", keys)
+	}
+}
+
+// TestCreateDepositInvoice_Key checks that a supplied key is sent and recorded on the DepositInvoice
+func TestCreateDepositInvoice_Key(t *testing.T) {
+	var keys []string
+	tps := newKeyServer(0, &keys)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL)
+	invoice, err := tpc.CreateDepositInvoiceContext(context.Background(), platform.Sats(2100), "memo", platform.LN,
+		platform.WithIdempotencyKey("order-42"),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if keys[0] != "order-42" || invoice.IdempotencyKey != "order-42" {
+		t.Errorf("Incorrect Keys: %v, %s", keys, invoice.IdempotencyKey)
+	}
+}
@@ -0,0 +1,68 @@
+package platform
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// TestMetrics checks the metrics served after a successful, a retried and a failed call
+func TestMetrics(t *testing.T) {
+	var hits int32
+	tps := newFlakyServer(1, http.StatusBadGateway, []byte(`{}`), &hits)
+	defer tps.Close()
+
+	metrics, err := platform.NewMetrics(0.1, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tpc := newClient(t, tps.URL, platform.WithMetrics(metrics), platform.WithRetryPolicy(fastRetryPolicy()))
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	unreachable := newClient(t, "http://127.0.0.1:1", platform.WithMetrics(metrics))
+	if _, err := unreachable.GetWithdrawal("wd_test"); err == nil {
+		t.Fatal("failed to fail")
+	}
+
+	mts := httptest.NewServer(metrics)
+	defer mts.Close()
+	res, err := http.Get(mts.URL)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	text := string(body)
+
+	for _, line := range []string{
+		`# TYPE platform_client_requests_total counter`,
+		`platform_client_requests_total{endpoint="AccountBalance",status="200"} 2`,
+		`platform_client_requests_total{endpoint="GetWithdrawal",status="error"} 1`,
+		`platform_client_request_duration_seconds_bucket{endpoint="AccountBalance",le="+Inf"} 2`,
+		`platform_client_request_duration_seconds_count{endpoint="AccountBalance"} 2`,
+		`platform_client_retries_total{endpoint="AccountBalance"} 1`,
+		`platform_client_in_flight_requests{endpoint="AccountBalance"} 0`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, text)
+		}
+	}
+	if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Incorrect Content-Type: %s", ct)
+	}
+}
+
+// TestNewMetricsFail_Buckets checks latency bucket validation
+func TestNewMetricsFail_Buckets(t *testing.T) {
+	if _, err := platform.NewMetrics(1, 0.5); err == nil {
+		t.Error("accepted decreasing buckets")
+	}
+}
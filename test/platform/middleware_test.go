@@ -0,0 +1,98 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// TestMiddleware_Order checks the order of Middleware and hooks, and what they can see of a call
+func TestMiddleware_Order(t *testing.T) {
+	var traceHeader string
+	tps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceHeader = r.Header.Get("X-Trace")
+		_, _ = w.Write([]byte(`{"id": "acc_test", "balance": 2100}`))
+	}))
+	defer tps.Close()
+
+	var order []string
+	named := func(name string) platform.Middleware {
+		return func(next platform.Handler) platform.Handler {
+			return func(ctx context.Context, call *platform.Call) error {
+				order = append(order, name+" before")
+				err := next(ctx, call)
+				order = append(order, name+" after")
+				return err
+			}
+		}
+	}
+	var result *platform.AccountSummary
+	var endpoint platform.Endpoint
+	inspect := func(next platform.Handler) platform.Handler {
+		return func(ctx context.Context, call *platform.Call) error {
+			endpoint = call.Endpoint
+			err := next(ctx, call)
+			result, _ = call.Result.(*platform.AccountSummary)
+			return err
+		}
+	}
+
+	tpc := newClient(t, tps.URL,
+		platform.WithMiddleware(named("outer"), named("inner")),
+		platform.WithMiddleware(inspect),
+		platform.WithBeforeRequest(func(_ context.Context, call *platform.Call, req *http.Request) error {
+			order = append(order, "before request")
+			req.Header.Set("X-Trace", string(call.Endpoint))
+			return nil
+		}),
+		platform.WithAfterResponse(func(_ context.Context, _ *platform.Call, res *http.Response, err error) {
+			order = append(order, "after response")
+		}),
+	)
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	want := []string{"outer before", "inner before", "before request", "after response", "inner after", "outer after"}
+	if len(order) != len(want) {
+		t.Fatalf("Incorrect Order: %v", order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Incorrect Order: %v", order)
+		}
+	}
+	if endpoint != platform.EndpointAccountBalance || traceHeader != "AccountBalance" {
+		t.Errorf("Incorrect Endpoint: %s, %s", endpoint, traceHeader)
+	}
+	if result == nil || result.Id != "acc_test" {
+		t.Errorf("Incorrect Result: %+v", result)
+	}
+}
+
+// TestBeforeRequestFail checks that an error from a BeforeRequestFunc aborts the call without sending it
+func TestBeforeRequestFail(t *testing.T) {
+	var hits int
+	tps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits++
+	}))
+	defer tps.Close()
+
+	denied := errors.New("denied by policy")
+	tpc := newClient(t, tps.URL,
+		platform.WithRetryPolicy(fastRetryPolicy()),
+		platform.WithBeforeRequest(func(context.Context, *platform.Call, *http.Request) error {
+			return denied
+		}),
+	)
+	if _, err := tpc.GetWithdrawal("wd_test"); !errors.Is(err, denied) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+	if hits != 0 {
+		t.Errorf("request was sent %d times", hits)
+	}
+}
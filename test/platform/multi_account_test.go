@@ -0,0 +1,85 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newAccountsServer responds to balance queries of the accounts in balances, and 404 for any other
+func newAccountsServer(balances map[string]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/accounts/"), "/")
+		balance, ok := balances[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"id": %q, "balance": %d}`, id, balance)
+	}))
+}
+
+// TestMultiClient checks per account clients and concurrent balance queries
+func TestMultiClient(t *testing.T) {
+	tps := newAccountsServer(map[string]int{"acc_treasury": 100, "acc_payouts": 200})
+	defer tps.Close()
+
+	metrics, _ := platform.NewMetrics()
+	mc, err := platform.NewMultiClient([]platform.Account{
+		{ID: "acc_treasury", APIKey: "secret1"},
+		{ID: "acc_payouts", APIKey: "secret2"},
+		{ID: "acc_float", APIKey: "secret3"},
+	}, platform.WithBaseURL(tps.URL), platform.WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	payouts, err := mc.ForAccount("acc_payouts")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	acct, err := payouts.AccountBalance()
+	if err != nil || acct.Id != "acc_payouts" {
+		t.Errorf("Incorrect Account: %+v, %v", acct, err)
+	}
+	if _, err := mc.ForAccount("acc_other"); !errors.Is(err, platform.ErrUnknownAccount) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+
+	balances, err := mc.AccountBalances(context.Background())
+	var errs platform.AccountErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || !errors.Is(errs["acc_float"], platform.ErrNotFound) {
+		t.Fatalf("Incorrect Error: %v", err)
+	}
+	if len(balances) != 2 || balances["acc_treasury"].Balance != platform.Sats(100) || balances["acc_payouts"].Balance != platform.Sats(200) {
+		t.Errorf("Incorrect Balances: %+v", balances)
+	}
+
+	var sb strings.Builder
+	if _, err := metrics.WriteTo(&sb); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(sb.String(), `platform_client_requests_total{endpoint="AccountBalance",status="200"} 3`) {
+		t.Errorf("metrics not shared between accounts:\n%s", sb.String())
+	}
+}
+
+// TestNewMultiClientFail checks account validation
+func TestNewMultiClientFail(t *testing.T) {
+	if _, err := platform.NewMultiClient(nil, platform.WithBaseURL("http://localhost")); err == nil {
+		t.Error("accepted no accounts")
+	}
+	_, err := platform.NewMultiClient([]platform.Account{
+		{ID: "acc_a", APIKey: "secret"},
+		{ID: "acc_a", APIKey: "secret"},
+	}, platform.WithBaseURL("http://localhost"))
+	if err == nil {
+		t.Error("accepted duplicate accounts")
+	}
+}
@@ -0,0 +1,81 @@
+package platform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SachinMeier/platform-client-go/pkg/log"
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// TestNewPlatformClientFail_MissingSettings checks that required options are enforced
+func TestNewPlatformClientFail_MissingSettings(t *testing.T) {
+	if _, err := platform.NewPlatformClient(platform.WithAccount("acc_test", "apisecret")); err == nil {
+		t.Error("created client without base URL")
+	}
+	if _, err := platform.NewPlatformClient(platform.WithBaseURL("http://localhost:8080")); err == nil {
+		t.Error("created client without account")
+	}
+}
+
+// TestWithBaseURL checks base URL validation and normalization
+func TestWithBaseURL(t *testing.T) {
+	cases := map[string]string{
+		"http://localhost:8080/":   "http://localhost:8080",
+		"api.platform.river.com":   "https://api.platform.river.com",
+		"https://example.com/v1//": "https://example.com/v1",
+	}
+	for raw, want := range cases {
+		tpc := newClient(t, raw)
+		if tpc.BaseURL != want {
+			t.Errorf("WithBaseURL(%q) = %q, want %q", raw, tpc.BaseURL, want)
+		}
+	}
+
+	for _, raw := range []string{"", "ftp://example.com", "http://", "http://example.com/?q=1"} {
+		_, err := platform.NewPlatformClient(
+			platform.WithBaseURL(raw),
+			platform.WithAccount("acc_test", "apisecret"),
+		)
+		if err == nil {
+			t.Errorf("WithBaseURL(%q) did not fail", raw)
+		}
+	}
+}
+
+// TestWithTimeout checks that WithTimeout does not modify a client passed with WithHTTPClient
+func TestWithTimeout(t *testing.T) {
+	hc := &http.Client{Timeout: time.Hour}
+	tpc := newClient(t, "http://localhost:8080",
+		platform.WithHTTPClient(hc),
+		platform.WithTimeout(time.Second),
+	)
+	if tpc.HTTPClient.Timeout != time.Second {
+		t.Errorf("Incorrect Timeout: %s", tpc.HTTPClient.Timeout)
+	}
+	if hc.Timeout != time.Hour {
+		t.Error("WithTimeout modified the caller's http.Client")
+	}
+	if _, err := platform.NewPlatformClient(platform.WithTimeout(0)); err == nil {
+		t.Error("accepted zero timeout")
+	}
+}
+
+// TestWithUserAgent checks the User-Agent header sent to the server
+func TestWithUserAgent(t *testing.T) {
+	var userAgent string
+	tps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+	}))
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithUserAgent("payouts/1.0"), platform.WithLogger(log.Nop))
+	if !tpc.Ping() {
+		t.Fatal("Ping Failed")
+	}
+	if userAgent != "payouts/1.0" {
+		t.Errorf("Incorrect User-Agent: %s", userAgent)
+	}
+}
@@ -1,7 +1,6 @@
 package platform
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -27,17 +26,26 @@ func newServer(status int, response []byte) *httptest.Server {
 	return tps
 }
 
+// newClient creates a PlatformClient for the test account pointed at baseUrl
+func newClient(t *testing.T, baseUrl string, opts ...platform.Option) *platform.PlatformClient {
+	t.Helper()
+	opts = append([]platform.Option{
+		platform.WithBaseURL(baseUrl),
+		platform.WithAccount("acc_test", "apisecret"),
+	}, opts...)
+	tpc, err := platform.NewPlatformClient(opts...)
+	if err != nil {
+		t.Fatalf("NewPlatformClient Failed: %s", err.Error())
+	}
+	return tpc
+}
+
 // TestPing tests pinging a mock server
 func TestPing(t *testing.T) {
 	tps := newServer(http.StatusOK, []byte(""))
 	defer tps.Close()
 
-	tpc := platform.NewPlatformClient(
-		context.Background(),
-		tps.URL,
-		"acc_test",
-		"apisecret",
-	)
+	tpc := newClient(t, tps.URL)
 	if !tpc.Ping() {
 		t.Error("Ping Failed")
 	}
@@ -45,12 +53,7 @@ func TestPing(t *testing.T) {
 
 // TestPingFail_NoServer runs ping against a nonexistent server
 func TestPingFail_NoServer(t *testing.T) {
-	tpc := platform.NewPlatformClient(
-		context.Background(),
-		"http://nohost:6969",
-		"acc_test",
-		"apisecret",
-	)
+	tpc := newClient(t, "http://nohost:6969")
 	if tpc.Ping() {
 		t.Error("Ping Failed")
 	}
@@ -61,12 +64,7 @@ func TestPingFail_403(t *testing.T) {
 	tps := newServer(http.StatusForbidden, []byte(""))
 	defer tps.Close()
 
-	tpc := platform.NewPlatformClient(
-		context.Background(),
-		"http://nohost:6969",
-		"acc_test",
-		"apisecret",
-	)
+	tpc := newClient(t, "http://nohost:6969")
 	if tpc.Ping() {
 		t.Error("Ping Failed")
 	}
@@ -76,19 +74,14 @@ func TestPingFail_403(t *testing.T) {
 func TestAccountBalance(t *testing.T) {
 	data := platform.AccountSummary{
 		Id:               "acc_satoshi",
-		Balance:          21000000,
-		AvailableBalance: 3092009,
+		Balance:          platform.Sats(21000000),
+		AvailableBalance: platform.Sats(3092009),
 	}
 	resp, _ := json.Marshal(data)
 
 	tps := newServer(http.StatusOK, []byte(resp))
 
-	tpc := platform.NewPlatformClient(
-		context.Background(),
-		tps.URL,
-		"apisecret",
-		"acc_test",
-	)
+	tpc := newClient(t, tps.URL)
 	if _, err := tpc.AccountBalance(); err != nil {
 		t.Error("GET Account Balance Failed")
 	}
@@ -98,12 +91,7 @@ func TestAccountBalance(t *testing.T) {
 func TestAccountBalanceFail_InvalidAccountId(t *testing.T) {
 	tps := newServer(http.StatusForbidden, []byte("Forbidden"))
 
-	tpc := platform.NewPlatformClient(
-		context.Background(),
-		tps.URL,
-		"acc_test",
-		"apisecret",
-	)
+	tpc := newClient(t, tps.URL)
 	_, err := tpc.AccountBalance()
 	if err == nil {
 		t.Errorf("failed to fail")
@@ -124,14 +112,9 @@ func TestCreateDepositInvoice(t *testing.T) {
 
 	tps := newServer(http.StatusOK, []byte(resp))
 
-	tpc := platform.NewPlatformClient(
-		context.Background(),
-		tps.URL,
-		"acc_test",
-		"apisecret",
-	)
+	tpc := newClient(t, tps.URL)
 
-	_, err := tpc.CreateDepositInvoice(250000, "memo", "LN")
+	_, err := tpc.CreateDepositInvoice(platform.Sats(250000), "memo", "LN")
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -140,14 +123,9 @@ func TestCreateDepositInvoice(t *testing.T) {
 func TestCreateDepositInvoiceFail_InvalidAmount(t *testing.T) {
 	tps := newServer(http.StatusInternalServerError, []byte("unable to process request"))
 
-	tpc := platform.NewPlatformClient(
-		context.Background(),
-		tps.URL,
-		"acc_test",
-		"apisecret",
-	)
+	tpc := newClient(t, tps.URL)
 
-	_, err := tpc.CreateDepositInvoice(-250, "neg amt", "LN")
+	_, err := tpc.CreateDepositInvoice(platform.Sats(-250), "neg amt", "LN")
 	if err == nil {
 		t.Error("failed to fail")
 	} else if msg := err.Error(); msg != "Error 500: unable to process request" {
@@ -158,14 +136,9 @@ func TestCreateDepositInvoiceFail_InvalidAmount(t *testing.T) {
 func TestCreateDepositInvoiceFail_InvalidNetwork(t *testing.T) {
 	tps := newServer(http.StatusInternalServerError, []byte("unable to process request"))
 
-	tpc := platform.NewPlatformClient(
-		context.Background(),
-		tps.URL,
-		"acc_test",
-		"apisecret",
-	)
+	tpc := newClient(t, tps.URL)
 
-	_, err := tpc.CreateDepositInvoice(-250, "neg amt", "LN")
+	_, err := tpc.CreateDepositInvoice(platform.Sats(-250), "neg amt", "LN")
 	if err == nil {
 		t.Error("failed to fail")
 	} else if msg := err.Error(); msg != "Error 500: unable to process request" {
@@ -195,12 +168,7 @@ func TestGetDepositInvoices(t *testing.T) {
 
 	tps := newServer(http.StatusOK, []byte(resp))
 
-	tpc := platform.NewPlatformClient(
-		context.Background(),
-		tps.URL,
-		"acc_test",
-		"apisecret",
-	)
+	tpc := newClient(t, tps.URL)
 
 	_, err := tpc.GetDepositInvoices(2, 1634975794000)
 	if err != nil {
@@ -219,7 +187,7 @@ func TestGetDeposits(t *testing.T) {
 					Network:   "LN",
 					Timestamp: 1634975794000,
 				},
-				Amount: 250000,
+				Amount: platform.Sats(250000),
 				Detail: platform.DepositDetail{
 					Network: "LN",
 					Proof:   "1c7272b3cb1d980b7701040e8afd537af886a437cd718ffdaf7c49c41171e11c",
@@ -228,7 +196,7 @@ func TestGetDeposits(t *testing.T) {
 			},
 			{
 				Id:     "acc_satoshi",
-				Amount: 250000,
+				Amount: platform.Sats(250000),
 				Invoice: platform.DepositInvoice{
 					Id:        "acc_satoshi",
 					Invoice:   "lnbc3500u1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpu9qrsgquk0rl77nj30yxdy8j9vdx85fkpmdla2087ne0xh8nhedh8w27kyke0lp53ut353s06fv3qfegext0eh0ymjpf39tuven09sam30g4vgpfna3rh",
@@ -248,12 +216,7 @@ func TestGetDeposits(t *testing.T) {
 
 	tps := newServer(http.StatusOK, []byte(resp))
 
-	tpc := platform.NewPlatformClient(
-		context.Background(),
-		tps.URL,
-		"acc_test",
-		"apisecret",
-	)
+	tpc := newClient(t, tps.URL)
 
 	_, err := tpc.GetDeposits(2, 0)
 	if err != nil {
@@ -264,12 +227,12 @@ func TestGetDeposits(t *testing.T) {
 func TestInitiateWithdrawal(t *testing.T) {
 
 	data := platform.Withdrawal{
-		Amount:   2100,
+		Amount:   platform.Sats(2100),
 		Currency: "BTC",
 		Details: platform.WithdrawalDetail{
 			Network:  "LN",
 			Invoice:  "lnbc3500u1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpu9qrsgquk0rl77nj30yxdy8j9vdx85fkpmdla2087ne0xh8nhedh8w27kyke0lp53ut353s06fv3qfegext0eh0ymjpf39tuven09sam30g4vgpfna3rh",
-			FeeLimit: 200,
+			FeeLimit: platform.Sats(200),
 		},
 		State: "PENDING",
 		Id:    "wd_tosilkroad",
@@ -279,14 +242,9 @@ func TestInitiateWithdrawal(t *testing.T) {
 
 	tps := newServer(http.StatusOK, []byte(resp))
 
-	tpc := platform.NewPlatformClient(
-		context.Background(),
-		tps.URL,
-		"acc_test",
-		"apisecret",
-	)
+	tpc := newClient(t, tps.URL)
 
-	_, err := tpc.InitiateWithdrawal(2100, "lnbc3500u1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpu9qrsgquk0rl77nj30yxdy8j9vdx85fkpmdla2087ne0xh8nhedh8w27kyke0lp53ut353s06fv3qfegext0eh0ymjpf39tuven09sam30g4vgpfna3rh", "BTC", "LN", 200)
+	_, err := tpc.InitiateWithdrawal(platform.Sats(2100), "lnbc3500u1pvjluezsp5zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zyg3zygspp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpu9qrsgquk0rl77nj30yxdy8j9vdx85fkpmdla2087ne0xh8nhedh8w27kyke0lp53ut353s06fv3qfegext0eh0ymjpf39tuven09sam30g4vgpfna3rh", "BTC", "LN", platform.Sats(200))
 	if err != nil {
 		t.Error(err.Error())
 	}
@@ -0,0 +1,301 @@
+package platform
+
+import (
+	"bufio"
+	b64 "encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// proxyLog records the users that connected through a proxy stand-in
+type proxyLog struct {
+	mu    sync.Mutex
+	users []string
+}
+
+func (pl *proxyLog) add(user string) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.users = append(pl.users, user)
+}
+
+func (pl *proxyLog) get() []string {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return append([]string(nil), pl.users...)
+}
+
+// pipe copies between two connections until either is closed
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+	a.Close()
+	b.Close()
+}
+
+// newConnectProxy starts an HTTP CONNECT proxy accepting user:password
+func newConnectProxy(t *testing.T, user, password string, log *proxyLog) *httptest.Server {
+	want := "Basic " + b64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != want {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		log.add(user)
+		w.WriteHeader(http.StatusOK)
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		if n := buf.Reader.Buffered(); n > 0 {
+			b, _ := buf.Reader.Peek(n)
+			_, _ = upstream.Write(b)
+		}
+		pipe(conn, upstream)
+	}))
+}
+
+// newSOCKS5Proxy starts a SOCKS5 proxy requiring username and password authentication (RFC 1928 and 1929),
+// accepting any username with password, and returns its address
+func newSOCKS5Proxy(t *testing.T, password string, log *proxyLog) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSOCKS5(conn, password, log)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveSOCKS5(conn net.Conn, password string, log *proxyLog) {
+	r := bufio.NewReader(conn)
+	fail := func() { conn.Close() }
+
+	// greeting: version, methods
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil || head[0] != 5 {
+		fail()
+		return
+	}
+	methods := make([]byte, head[1])
+	if _, err := io.ReadFull(r, methods); err != nil {
+		fail()
+		return
+	}
+	_, _ = conn.Write([]byte{5, 2})
+
+	// username and password
+	readString := func() (string, error) {
+		n, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return string(b), err
+	}
+	if v, err := r.ReadByte(); err != nil || v != 1 {
+		fail()
+		return
+	}
+	user, err := readString()
+	if err != nil {
+		fail()
+		return
+	}
+	pass, err := readString()
+	if err != nil || pass != password {
+		_, _ = conn.Write([]byte{1, 1})
+		fail()
+		return
+	}
+	_, _ = conn.Write([]byte{1, 0})
+
+	// connect request: version, command, reserved, address type, address, port
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(r, req); err != nil || req[1] != 1 {
+		fail()
+		return
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		ip := make([]byte, 4)
+		_, _ = io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	case 3:
+		host, _ = readString()
+	case 4:
+		ip := make([]byte, 16)
+		_, _ = io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(r, port); err != nil {
+		fail()
+		return
+	}
+	upstream, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		fail()
+		return
+	}
+	log.add(user)
+	_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	pipe(&bufferedConn{Conn: conn, r: r}, upstream)
+}
+
+// bufferedConn reads from r, which buffers the Conn
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (bc *bufferedConn) Read(p []byte) (int, error) {
+	return bc.r.Read(p)
+}
+
+// TestConnectProxy checks that https requests tunnel through an authenticated HTTP proxy
+func TestConnectProxy(t *testing.T) {
+	tps := newTLSServer([]byte(`{"id": "acc_test", "balance": 2100}`), nil)
+	defer tps.Close()
+	var log proxyLog
+	proxy := newConnectProxy(t, "egress", "p@ss", &log)
+	defer proxy.Close()
+
+	tpc := newClient(t, tps.URL,
+		platform.WithRootCAs(rootCAs(tps)),
+		platform.WithProxy("http://egress:p%40ss@"+proxy.Listener.Addr().String()),
+	)
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if users := log.get(); len(users) != 1 || users[0] != "egress" {
+		t.Errorf("Incorrect Proxy Connections: %v", users)
+	}
+
+	tpc = newClient(t, tps.URL,
+		platform.WithRootCAs(rootCAs(tps)),
+		platform.WithProxy("http://egress:wrong@"+proxy.Listener.Addr().String()),
+	)
+	if _, err := tpc.AccountBalance(); err == nil {
+		t.Error("connected with wrong proxy password")
+	}
+}
+
+// TestSOCKS5Proxy checks requests through an authenticated SOCKS5 proxy
+func TestSOCKS5Proxy(t *testing.T) {
+	tps := newServer(http.StatusOK, []byte(`{"id": "acc_test", "balance": 2100}`))
+	defer tps.Close()
+	var log proxyLog
+	addr := newSOCKS5Proxy(t, "tor", &log)
+
+	tpc := newClient(t, tps.URL, platform.WithProxy("socks5h://isolated:tor@"+addr))
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if users := log.get(); len(users) != 1 || users[0] != "isolated" {
+		t.Errorf("Incorrect Proxy Connections: %v", users)
+	}
+}
+
+// TestAccountProxies checks that accounts of a MultiClient use their own proxy
+func TestAccountProxies(t *testing.T) {
+	tps := newAccountsServer(map[string]int{"acc_direct": 100, "acc_tor": 200, "acc_tor2": 300})
+	defer tps.Close()
+	var log proxyLog
+	addr := newSOCKS5Proxy(t, "tor", &log)
+
+	mc, err := platform.NewMultiClient([]platform.Account{
+		{ID: "acc_direct", APIKey: "secret1"},
+		{ID: "acc_tor", APIKey: "secret2", Proxy: "socks5://acc_tor:tor@" + addr},
+		{ID: "acc_tor2", APIKey: "secret3", Proxy: "socks5://acc_tor2:tor@" + addr},
+	}, platform.WithBaseURL(tps.URL))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, id := range mc.Accounts() {
+		tpc, _ := mc.ForAccount(id)
+		if _, err := tpc.AccountBalance(); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if users := log.get(); len(users) != 2 || users[0] != "acc_tor" || users[1] != "acc_tor2" {
+		t.Errorf("Incorrect Proxy Connections: %v", users)
+	}
+}
+
+// TestAccountProxies_Inherited checks that accounts without their own proxy use the proxy of the MultiClient
+func TestAccountProxies_Inherited(t *testing.T) {
+	tps := newAccountsServer(map[string]int{"acc_shared": 100, "acc_tor": 200})
+	defer tps.Close()
+	var log proxyLog
+	addr := newSOCKS5Proxy(t, "tor", &log)
+
+	mc, err := platform.NewMultiClient([]platform.Account{
+		{ID: "acc_shared", APIKey: "secret1"},
+		{ID: "acc_tor", APIKey: "secret2", Proxy: "socks5://acc_tor:tor@" + addr},
+	}, platform.WithBaseURL(tps.URL), platform.WithProxy("socks5://shared:tor@"+addr))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, id := range mc.Accounts() {
+		tpc, _ := mc.ForAccount(id)
+		if _, err := tpc.AccountBalance(); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if users := log.get(); len(users) != 2 || users[0] != "shared" || users[1] != "acc_tor" {
+		t.Errorf("Incorrect Proxy Connections: %v", users)
+	}
+}
+
+// TestWithProxyFail checks proxy URL validation
+func TestWithProxyFail(t *testing.T) {
+	for _, proxy := range []string{"", "ftp://proxy:21", "socks5://", "http://proxy:3128/path"} {
+		if _, err := platform.NewPlatformClient(platform.WithProxy(proxy)); err == nil {
+			t.Errorf("accepted proxy %q", proxy)
+		}
+	}
+	_, err := platform.NewMultiClient([]platform.Account{
+		{ID: "acc_a", APIKey: "secret", Proxy: "gopher://proxy"},
+	}, platform.WithBaseURL("http://localhost"))
+	if err == nil {
+		t.Error("accepted account proxy")
+	}
+}
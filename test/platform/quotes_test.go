@@ -0,0 +1,207 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newQuoteConverter returns a FiatConverter quoting USD at 50000 per bitcoin, 2000 sats a dollar
+func newQuoteConverter(t *testing.T) *platform.FiatConverter {
+	provider, err := platform.NewStaticRateProvider(mustRate(t, "USD", "50000", time.Now()))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return &platform.FiatConverter{Provider: provider, Currency: "USD", QuoteLock: time.Minute, QuoteTolerance: 1}
+}
+
+// TestCreateFiatDepositInvoice checks that the quoted amount is requested and the quote recorded
+func TestCreateFiatDepositInvoice(t *testing.T) {
+	var sent map[string]interface{}
+	tps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &sent)
+		_, _ = w.Write([]byte(`{"id": "di_1", "destination": "lnbc1", "network": "LN"}`))
+	}))
+	defer tps.Close()
+
+	price, err := platform.ParseFiatAmount("19.99", "usd")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tpc := newClient(t, tps.URL)
+	invoice, err := tpc.CreateFiatDepositInvoice(newQuoteConverter(t), price, "order 42", platform.LN)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if sent["amount"] != float64(39980) {
+		t.Errorf("Incorrect Amount Sent: %v", sent["amount"])
+	}
+	quote := invoice.Quote
+	if quote == nil {
+		t.Fatal("no quote recorded")
+	}
+	if quote.Amount != platform.Sats(39980) || quote.Price.String() != "19.99 USD" || quote.InvoiceId != "di_1" ||
+		quote.Rate.PerBTC() != "50000" || quote.ExpiresAt.Sub(quote.CreatedAt) != time.Minute {
+		t.Errorf("Incorrect Quote: %+v", quote)
+	}
+
+	// a stored quote judges payments the same way
+	b, err := json.Marshal(quote)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var stored platform.Quote
+	if err := json.Unmarshal(b, &stored); err != nil {
+		t.Fatal(err.Error())
+	}
+	if stored.Amount != quote.Amount || stored.Rate.PerBTC() != "50000" || stored.Price != quote.Price {
+		t.Errorf("Incorrect Stored Quote: %s", b)
+	}
+}
+
+// TestQuoteJudge checks the judgement of exact, under and over payments
+func TestQuoteJudge(t *testing.T) {
+	price, err := platform.ParseFiatAmount("10", "USD")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	quote, err := newQuoteConverter(t).Quote(context.Background(), price)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	quote.InvoiceId = "di_1"
+	if quote.Amount != platform.Sats(20000) {
+		t.Fatalf("Incorrect Quote Amount: %s", quote.Amount)
+	}
+
+	now := time.Now()
+	cases := []struct {
+		paid       platform.Amount
+		status     platform.PaymentStatus
+		difference string
+	}{
+		{platform.Sats(20000), platform.PaymentExact, "0.00 USD"},
+		// within the tolerance of one cent
+		{platform.Sats(19980), platform.PaymentExact, "-0.01 USD"},
+		{platform.Sats(19000), platform.PaymentUnderpaid, "-0.50 USD"},
+		{platform.Sats(21000), platform.PaymentOverpaid, "0.50 USD"},
+	}
+	for _, c := range cases {
+		j, err := quote.Judge(c.paid, now)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if j.Status != c.status || j.Difference.String() != c.difference || j.Late {
+			t.Errorf("%s: Incorrect Judgement: %s, %s", c.paid, j.Status, j.Difference)
+		}
+	}
+
+	// deposits to other invoices are ignored, and a deposit after the lock is late
+	late := quote.ExpiresAt.Add(time.Second)
+	j, err := quote.JudgeDeposits(
+		platform.Deposit{Amount: platform.Sats(15000), Invoice: platform.DepositInvoice{Id: "di_1"}, Timestamp: int(now.UnixNano() / int64(time.Millisecond))},
+		platform.Deposit{Amount: platform.Sats(5000), Invoice: platform.DepositInvoice{Id: "di_2"}, Timestamp: int(now.UnixNano() / int64(time.Millisecond))},
+		platform.Deposit{Amount: platform.Sats(5000), Invoice: platform.DepositInvoice{Id: "di_1"}, Timestamp: int(late.UnixNano() / int64(time.Millisecond))},
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if j.Status != platform.PaymentExact || j.Paid != platform.Sats(20000) || !j.Late {
+		t.Errorf("Incorrect Judgement of Deposits: %+v", j)
+	}
+}
+
+// TestQuoteFail checks the prices that cannot be quoted
+func TestQuoteFail(t *testing.T) {
+	fc := newQuoteConverter(t)
+	if _, err := platform.ParseFiatAmount("1.999", "USD"); err == nil {
+		t.Error("parsed a fraction of a cent")
+	}
+	zero, _ := platform.ParseFiatAmount("0", "USD")
+	if _, err := fc.Quote(context.Background(), zero); !errors.Is(err, platform.ErrInvalidRequest) {
+		t.Errorf("Incorrect Error of zero price: %v", err)
+	}
+	euros, _ := platform.ParseFiatAmount("5", "EUR")
+	if _, err := fc.Quote(context.Background(), euros); !errors.Is(err, platform.ErrInvalidRequest) {
+		t.Errorf("Incorrect Error of other currency: %v", err)
+	}
+	// a literal price must carry the decimals of its currency, or 1999 cents would be quoted as 1999 dollars
+	literal := platform.FiatAmount{Currency: "USD", Minor: 1999}
+	if _, err := fc.Quote(context.Background(), literal); !errors.Is(err, platform.ErrInvalidRequest) {
+		t.Errorf("Incorrect Error of price without decimals: %v", err)
+	}
+	rate := mustRate(t, "USD", "50000", time.Now())
+	if _, err := platform.FromFiat(literal, rate, platform.RoundHalfEven); !errors.Is(err, platform.ErrInvalidRequest) {
+		t.Errorf("Incorrect FromFiat Error: %v", err)
+	}
+	literal.Decimals = 2
+	if quote, err := fc.Quote(context.Background(), literal); err != nil || quote.Amount != platform.Sats(39980) {
+		t.Errorf("Incorrect Quote of literal price: %+v, %v", quote, err)
+	}
+	stored := platform.Quote{Price: platform.FiatAmount{Currency: "USD", Minor: 1999}, Amount: platform.Sats(3998000), Rate: rate}
+	if _, err := stored.Judge(platform.Sats(3998000), time.Now()); !errors.Is(err, platform.ErrInvalidRequest) {
+		t.Errorf("Incorrect Judge Error: %v", err)
+	}
+	fc.Currency = "EUR"
+	if _, err := fc.Quote(context.Background(), euros); !errors.Is(err, platform.ErrNoRate) {
+		t.Errorf("Incorrect Error of unknown currency: %v", err)
+	}
+}
+
+// TestCreateQuotedDepositInvoice checks that a failed fiat invoice is retried for the quoted amount
+func TestCreateQuotedDepositInvoice(t *testing.T) {
+	var amounts []interface{}
+	var keys []string
+	tps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sent map[string]interface{}
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &sent)
+		amounts = append(amounts, sent["amount"])
+		keys = append(keys, r.Header.Get(platform.IdempotencyKeyHeader))
+		if len(amounts) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "di_1", "destination": "lnbc1", "network": "LN"}`))
+	}))
+	defer tps.Close()
+
+	price, err := platform.ParseFiatAmount("19.99", "USD")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tpc := newClient(t, tps.URL)
+	failed, err := tpc.CreateFiatDepositInvoice(newQuoteConverter(t), price, "order 42", platform.LN)
+	if err == nil || failed.Quote == nil || failed.IdempotencyKey == "" {
+		t.Fatalf("Incorrect Failed Invoice: %+v, %v", failed, err)
+	}
+	invoice, err := tpc.CreateQuotedDepositInvoiceContext(context.Background(), *failed.Quote, "order 42", platform.LN,
+		platform.WithIdempotencyKey(failed.IdempotencyKey),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(amounts) != 2 || amounts[1] != amounts[0] || keys[1] != keys[0] {
+		t.Errorf("Incorrect Requests: %v, %v", amounts, keys)
+	}
+	if invoice.Quote == nil || invoice.Quote.Id != failed.Quote.Id || invoice.Quote.InvoiceId != "di_1" {
+		t.Errorf("Incorrect Quote: %+v", invoice.Quote)
+	}
+
+	expired := *failed.Quote
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	if _, err := tpc.CreateQuotedDepositInvoice(expired, "order 42", platform.LN); !errors.Is(err, platform.ErrInvalidRequest) {
+		t.Errorf("Incorrect Error of expired quote: %v", err)
+	}
+	if len(amounts) != 2 {
+		t.Errorf("expired quote sent: %v", amounts)
+	}
+}
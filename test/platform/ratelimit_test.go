@@ -0,0 +1,90 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// TestRateLimit checks that concurrent calls are spread out by the limiter
+func TestRateLimit(t *testing.T) {
+	tps := newServer(http.StatusOK, []byte(`{}`))
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithRateLimit(platform.RateLimit{Rate: 50, Burst: 1}))
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := tpc.GetWithdrawal("wd_test"); err != nil {
+				t.Error(err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+	// the first call is allowed immediately, the other 4 are 20ms apart
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond {
+		t.Errorf("calls were not limited: %s", elapsed)
+	}
+}
+
+// TestEndpointRateLimit_Deadline checks that a call whose deadline is shorter than its wait fails without waiting
+func TestEndpointRateLimit_Deadline(t *testing.T) {
+	tps := newServer(http.StatusOK, []byte(`{}`))
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL,
+		platform.WithEndpointRateLimit(platform.GroupWithdrawals, platform.RateLimit{Rate: 0.1, Burst: 1}),
+	)
+	if _, err := tpc.InitiateWithdrawal(platform.Sats(2100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	_, err := tpc.InitiateWithdrawalContext(ctx, platform.Sats(2100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Incorrect Error: %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("call waited for a token it could not get")
+	}
+
+	// reads are not limited by the withdrawals group
+	if _, err := tpc.GetWithdrawalContext(ctx, "wd_test"); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+// TestRateLimit_429 checks that the limiter pauses for the Retry-After of a 429 response
+func TestRateLimit_429(t *testing.T) {
+	var hits int32
+	tps := newFlakyServer(1, http.StatusTooManyRequests, []byte(`{}`), &hits)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithRateLimit(platform.RateLimit{Rate: 1000, Burst: 10}))
+	if _, err := tpc.AccountBalance(); !errors.Is(err, platform.ErrRateLimited) {
+		t.Fatalf("Incorrect Error: %v", err)
+	}
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+// TestWithRateLimitFail_Invalid checks rate limit validation
+func TestWithRateLimitFail_Invalid(t *testing.T) {
+	if _, err := platform.NewPlatformClient(platform.WithRateLimit(platform.RateLimit{Rate: 0, Burst: 1})); err == nil {
+		t.Error("accepted zero rate")
+	}
+	if _, err := platform.NewPlatformClient(platform.WithEndpointRateLimit("other", platform.RateLimit{Rate: 1, Burst: 1})); err == nil {
+		t.Error("accepted unknown group")
+	}
+}
@@ -0,0 +1,151 @@
+package platform
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newCountingServer counts the requests it receives per method, waiting delay before responding to GETs
+func newCountingServer(delay time.Duration, gets, posts *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(gets, 1)
+			time.Sleep(delay)
+		} else {
+			atomic.AddInt32(posts, 1)
+		}
+		_, _ = w.Write([]byte(`{"id": "acc_test", "balance": 2100}`))
+	}))
+}
+
+// TestRequestCoalescing checks that concurrent identical reads share one request
+func TestRequestCoalescing(t *testing.T) {
+	var gets, posts int32
+	tps := newCountingServer(50*time.Millisecond, &gets, &posts)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithRequestCoalescing())
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			acct, err := tpc.AccountBalance()
+			if err != nil {
+				t.Error(err.Error())
+			} else if acct.Balance != platform.Sats(2100) {
+				t.Errorf("Incorrect Balance: %s", acct.Balance)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&gets); n != 1 {
+		t.Errorf("Incorrect Requests: %d", n)
+	}
+}
+
+// TestReadCache checks that reads are cached until their TTL or a withdrawal
+func TestReadCache(t *testing.T) {
+	var gets, posts int32
+	tps := newCountingServer(0, &gets, &posts)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithReadCache(50*time.Millisecond))
+	for i := 0; i < 3; i++ {
+		if _, err := tpc.AccountBalance(); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if n := atomic.LoadInt32(&gets); n != 1 {
+		t.Errorf("Incorrect Requests before withdrawal: %d", n)
+	}
+
+	if _, err := tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if n := atomic.LoadInt32(&gets); n != 2 {
+		t.Errorf("Incorrect Requests after withdrawal: %d", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if n := atomic.LoadInt32(&gets); n != 3 {
+		t.Errorf("Incorrect Requests after TTL: %d", n)
+	}
+
+	// uncached endpoints are always requested
+	for i := 0; i < 2; i++ {
+		if _, err := tpc.GetWithdrawal("wd_test"); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if n := atomic.LoadInt32(&gets); n != 5 {
+		t.Errorf("Incorrect Requests of uncached endpoint: %d", n)
+	}
+}
+
+// TestReadCache_ConcurrentWithdrawal checks that a read overlapping a withdrawal does not cache the balance before it
+func TestReadCache_ConcurrentWithdrawal(t *testing.T) {
+	balance := int64(2100)
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	var held int32
+	tps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			atomic.AddInt64(&balance, -100)
+			_, _ = w.Write([]byte(`{"id": "wd_test"}`))
+			return
+		}
+		body := fmt.Sprintf(`{"id": "acc_test", "balance": %d}`, atomic.LoadInt64(&balance))
+		// the first read answers with the balance before the withdrawal, once the withdrawal is done
+		if atomic.CompareAndSwapInt32(&held, 0, 1) {
+			close(arrived)
+			<-release
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithReadCache(time.Minute), platform.WithRequestCoalescing())
+	done := make(chan error)
+	go func() {
+		_, err := tpc.AccountBalance()
+		done <- err
+	}()
+	<-arrived
+	if _, err := tpc.InitiateWithdrawal(platform.Sats(100), "lnbc1", platform.BTC, platform.LN, platform.Sats(10)); err != nil {
+		t.Fatal(err.Error())
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err.Error())
+	}
+
+	acct, err := tpc.AccountBalance()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if acct.Balance != platform.Sats(2000) {
+		t.Errorf("Incorrect Balance after withdrawal: %s", acct.Balance)
+	}
+}
+
+// TestWithReadCacheFail_Mutating checks that mutating endpoints cannot be cached
+func TestWithReadCacheFail_Mutating(t *testing.T) {
+	_, err := platform.NewPlatformClient(platform.WithReadCache(time.Second, platform.EndpointInitiateWithdrawal))
+	if err == nil {
+		t.Error("accepted mutating endpoint")
+	}
+}
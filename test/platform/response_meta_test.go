@@ -0,0 +1,104 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newMetaServer responds with a request id, a Date and the given rate-limit headers
+func newMetaServer(status int, body string, date time.Time, rateLimit map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("X-Request-Id", "req_123")
+		w.Header().Set("Date", date.UTC().Format(http.TimeFormat))
+		for key, value := range rateLimit {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+// TestResponseMeta checks the metadata of a successful call
+func TestResponseMeta(t *testing.T) {
+	date := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	reset := date.Add(time.Hour)
+	tps := newMetaServer(http.StatusOK, `{"id": "acc_test", "balance": 2100}`, date, map[string]string{
+		"X-RateLimit-Limit":     "100",
+		"X-RateLimit-Remaining": "42",
+		"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+	})
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL)
+	var meta platform.ResponseMeta
+	summary, err := tpc.AccountBalanceContext(context.Background(), platform.WithResponseMeta(&meta))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if summary.Balance != platform.Sats(2100) {
+		t.Errorf("Incorrect Balance: %s", summary.Balance)
+	}
+	if meta.StatusCode != http.StatusOK || meta.RequestID != "req_123" || !meta.Date.Equal(date) ||
+		meta.Attempts != 1 || meta.Latency <= 0 || meta.Cached {
+		t.Errorf("Incorrect ResponseMeta: %+v", meta)
+	}
+	if meta.RateLimit == nil || meta.RateLimit.Limit != 100 || meta.RateLimit.Remaining != 42 || !meta.RateLimit.Reset.Equal(reset) {
+		t.Errorf("Incorrect RateLimit: %+v", meta.RateLimit)
+	}
+}
+
+// TestResponseMeta_Error checks the metadata of a failed call, with a reset given in seconds
+func TestResponseMeta_Error(t *testing.T) {
+	date := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tps := newMetaServer(http.StatusTooManyRequests, `{"code": "rate_limited"}`, date, map[string]string{
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "30",
+	})
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL)
+	var meta platform.ResponseMeta
+	_, err := tpc.GetWithdrawalContext(context.Background(), "wd_test", platform.WithResponseMeta(&meta))
+	if !errors.Is(err, platform.ErrRateLimited) {
+		t.Fatalf("Incorrect Error: %v", err)
+	}
+	if meta.StatusCode != http.StatusTooManyRequests || meta.RequestID != "req_123" {
+		t.Errorf("Incorrect ResponseMeta: %+v", meta)
+	}
+	if meta.RateLimit == nil || meta.RateLimit.Limit != 0 || meta.RateLimit.Remaining != 0 || !meta.RateLimit.Reset.Equal(date.Add(30*time.Second)) {
+		t.Errorf("Incorrect RateLimit: %+v", meta.RateLimit)
+	}
+}
+
+// TestResponseMeta_NoResponse checks the metadata of calls without a response of their own
+func TestResponseMeta_NoResponse(t *testing.T) {
+	var meta platform.ResponseMeta
+	tpc := newClient(t, "http://127.0.0.1:1")
+	if _, err := tpc.AccountBalanceContext(context.Background(), platform.WithResponseMeta(&meta)); err == nil {
+		t.Fatal("call succeeded")
+	}
+	if meta.StatusCode != 0 || meta.Attempts != 1 || meta.Cached || meta.RateLimit != nil {
+		t.Errorf("Incorrect ResponseMeta: %+v", meta)
+	}
+
+	tps := newServer(http.StatusOK, []byte(`{"id": "acc_test", "balance": 2100}`))
+	defer tps.Close()
+	tpc = newClient(t, tps.URL, platform.WithReadCache(time.Minute))
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	meta = platform.ResponseMeta{}
+	if _, err := tpc.AccountBalanceContext(context.Background(), platform.WithResponseMeta(&meta)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !meta.Cached || meta.StatusCode != 0 {
+		t.Errorf("Incorrect ResponseMeta of cached call: %+v", meta)
+	}
+}
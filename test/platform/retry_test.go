@@ -0,0 +1,125 @@
+package platform
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newFlakyServer fails the first failures requests with status, then responds 200 with response
+func newFlakyServer(failures int32, status int, response []byte, hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(hits, 1) <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write(response)
+	}))
+}
+
+// fastRetryPolicy is DefaultRetryPolicy with millisecond backoff
+func fastRetryPolicy() platform.RetryPolicy {
+	policy := platform.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+// TestRetry_GET checks that a GET is retried after 502 responses
+func TestRetry_GET(t *testing.T) {
+	var hits int32
+	tps := newFlakyServer(2, http.StatusBadGateway, []byte(`{"id": "acc_test"}`), &hits)
+	defer tps.Close()
+
+	var attempts []platform.Attempt
+	policy := fastRetryPolicy()
+	policy.OnAttempt = func(a platform.Attempt) {
+		attempts = append(attempts, a)
+	}
+	tpc := newClient(t, tps.URL, platform.WithRetryPolicy(policy))
+
+	acct, err := tpc.AccountBalance()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if acct.Id != "acc_test" {
+		t.Errorf("Incorrect Account: %s", acct.Id)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("Incorrect Attempts: %d", len(attempts))
+	}
+	if !attempts[0].Retrying || attempts[0].StatusCode != http.StatusBadGateway || attempts[2].Retrying {
+		t.Errorf("Incorrect Attempts: %+v", attempts)
+	}
+}
+
+// TestRetry_GiveUp checks that retries stop after MaxAttempts
+func TestRetry_GiveUp(t *testing.T) {
+	var hits int32
+	tps := newFlakyServer(10, http.StatusServiceUnavailable, nil, &hits)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithRetryPolicy(fastRetryPolicy()))
+	if _, err := tpc.GetWithdrawal("wd_test"); err == nil {
+		t.Error("failed to fail")
+	}
+	if hits := atomic.LoadInt32(&hits); hits != 4 {
+		t.Errorf("Incorrect Attempts: %d", hits)
+	}
+}
+
+// TestRetry_UnsafePOST checks that a POST without an idempotency key is sent once
+func TestRetry_UnsafePOST(t *testing.T) {
+	var hits int32
+	tps := newFlakyServer(1, http.StatusBadGateway, []byte(`{}`), &hits)
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithRetryPolicy(fastRetryPolicy()))
+	if _, err := tpc.SubscribeToWebhook("https://example.com/hook"); err == nil {
+		t.Error("failed to fail")
+	}
+	if hits := atomic.LoadInt32(&hits); hits != 1 {
+		t.Errorf("Incorrect Attempts: %d", hits)
+	}
+}
+
+// TestRetry_POSTWithIdempotencyKey checks that a POST with an idempotency key is retried with its body
+func TestRetry_POSTWithIdempotencyKey(t *testing.T) {
+	var hits int32
+	var bodies []int64
+	tps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bodies = append(bodies, r.ContentLength)
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer tps.Close()
+
+	tpc := newClient(t, tps.URL, platform.WithRetryPolicy(fastRetryPolicy()))
+	_, err := tpc.SubscribeToWebhookContext(context.Background(), "https://example.com/hook",
+		platform.WithHeader(platform.IdempotencyKeyHeader, "hook-1"),
+	)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || bodies[0] == 0 {
+		t.Errorf("Incorrect Bodies: %v", bodies)
+	}
+}
+
+// TestWithRetryPolicyFail_Invalid checks retry policy validation
+func TestWithRetryPolicyFail_Invalid(t *testing.T) {
+	policy := platform.DefaultRetryPolicy()
+	policy.Jitter = 2
+	if _, err := platform.NewPlatformClient(platform.WithRetryPolicy(policy)); err == nil {
+		t.Error("accepted invalid policy")
+	}
+}
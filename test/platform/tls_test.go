@@ -0,0 +1,214 @@
+package platform
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	b64 "encoding/base64"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newTLSServer starts a TLS server responding with body, configured by configure before it starts
+func newTLSServer(body []byte, configure func(*tls.Config)) *httptest.Server {
+	tps := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			w.Header().Set("X-Client-Cert", r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+		_, _ = w.Write(body)
+	}))
+	tps.TLS = &tls.Config{}
+	if configure != nil {
+		configure(tps.TLS)
+	}
+	tps.StartTLS()
+	return tps
+}
+
+// rootCAs returns a pool trusting the certificate of tps
+func rootCAs(tps *httptest.Server) *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(tps.Certificate())
+	return pool
+}
+
+// newClientCertificate creates a self signed client certificate named cn
+func newClientCertificate(t *testing.T, cn string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// TestRootCAs checks that a server is only trusted with its CA
+func TestRootCAs(t *testing.T) {
+	tps := newTLSServer([]byte(`{"id": "acc_test", "balance": 2100}`), nil)
+	defer tps.Close()
+
+	if _, err := newClient(t, tps.URL).AccountBalance(); err == nil {
+		t.Error("trusted unknown CA")
+	}
+	if _, err := newClient(t, tps.URL, platform.WithRootCAs(rootCAs(tps))).AccountBalance(); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+// TestPinnedKeys checks pin matches and the error of a mismatch
+func TestPinnedKeys(t *testing.T) {
+	tps := newTLSServer([]byte(`{"id": "acc_test", "balance": 2100}`), nil)
+	defer tps.Close()
+
+	pin := platform.SPKIPin(tps.Certificate())
+	tpc := newClient(t, tps.URL, platform.WithRootCAs(rootCAs(tps)), platform.WithPinnedKeys(pin))
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Error(err.Error())
+	}
+
+	other := sha256.Sum256([]byte("backup key"))
+	tpc = newClient(t, tps.URL,
+		platform.WithRootCAs(rootCAs(tps)),
+		platform.WithPinnedKeys(b64.StdEncoding.EncodeToString(other[:])),
+	)
+	_, err := tpc.AccountBalance()
+	var pinErr *platform.PinError
+	if !errors.Is(err, platform.ErrPinMismatch) || !errors.As(err, &pinErr) {
+		t.Fatalf("Incorrect Error: %v", err)
+	}
+	if len(pinErr.Presented) != 1 || pinErr.Presented[0] != pin {
+		t.Errorf("Incorrect Presented Pins: %v", pinErr.Presented)
+	}
+
+	if _, err := platform.NewPlatformClient(platform.WithPinnedKeys("sha256/abc")); err == nil {
+		t.Error("accepted invalid pin")
+	}
+}
+
+// TestPinnedKeysFail_AppendedCertificate checks that a pinned certificate appended to a chain it does not verify is not matched
+func TestPinnedKeysFail_AppendedCertificate(t *testing.T) {
+	river := newTLSServer([]byte(`{"id": "acc_test", "balance": 2100}`), nil)
+	defer river.Close()
+
+	// the attacker's server has a certificate the client trusts, followed by the pinned one
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "attacker"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	attacker := newTLSServer([]byte(`{"id": "acc_test", "balance": 2100}`), func(c *tls.Config) {
+		c.Certificates = []tls.Certificate{{Certificate: [][]byte{der, river.Certificate().Raw}, PrivateKey: key}}
+	})
+	defer attacker.Close()
+
+	tpc := newClient(t, attacker.URL, platform.WithRootCAs(rootCAs(attacker)), platform.WithPinnedKeys(platform.SPKIPin(river.Certificate())))
+	_, err = tpc.AccountBalance()
+	var pinErr *platform.PinError
+	if !errors.As(err, &pinErr) {
+		t.Fatalf("Incorrect Error: %v", err)
+	}
+	if len(pinErr.Presented) != 2 || pinErr.Presented[1] != platform.SPKIPin(river.Certificate()) {
+		t.Errorf("Incorrect Presented Pins: %v", pinErr.Presented)
+	}
+}
+
+// TestClientCertificate checks mutual TLS
+func TestClientCertificate(t *testing.T) {
+	tps := newTLSServer([]byte(`{"id": "acc_test", "balance": 2100}`), func(c *tls.Config) {
+		c.ClientAuth = tls.RequireAnyClientCert
+	})
+	defer tps.Close()
+
+	var cn string
+	record := platform.WithAfterResponse(func(_ context.Context, _ *platform.Call, res *http.Response, _ error) {
+		if res != nil {
+			cn = res.Header.Get("X-Client-Cert")
+		}
+	})
+	if _, err := newClient(t, tps.URL, platform.WithRootCAs(rootCAs(tps))).AccountBalance(); err == nil {
+		t.Error("connected without client certificate")
+	}
+	tpc := newClient(t, tps.URL,
+		platform.WithRootCAs(rootCAs(tps)),
+		platform.WithClientCertificate(newClientCertificate(t, "acc_test")),
+		record,
+	)
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if cn != "acc_test" {
+		t.Errorf("Incorrect Client Certificate: %q", cn)
+	}
+}
+
+// TestMinTLSVersion checks that older servers are refused
+func TestMinTLSVersion(t *testing.T) {
+	tps := newTLSServer([]byte(`{"id": "acc_test", "balance": 2100}`), func(c *tls.Config) {
+		c.MaxVersion = tls.VersionTLS12
+	})
+	defer tps.Close()
+
+	if _, err := newClient(t, tps.URL, platform.WithRootCAs(rootCAs(tps)), platform.WithMinTLSVersion(tls.VersionTLS12)).AccountBalance(); err != nil {
+		t.Error(err.Error())
+	}
+	if _, err := newClient(t, tps.URL, platform.WithRootCAs(rootCAs(tps)), platform.WithMinTLSVersion(tls.VersionTLS13)).AccountBalance(); err == nil {
+		t.Error("accepted TLS 1.2 server")
+	}
+	if _, err := platform.NewPlatformClient(platform.WithMinTLSVersion(0x0200)); err == nil {
+		t.Error("accepted unknown TLS version")
+	}
+}
+
+// roundTripperFunc is an http.RoundTripper that is not an *http.Transport
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestTLSOptionsFail_Transport checks that TLS options cannot configure a custom RoundTripper
+func TestTLSOptionsFail_Transport(t *testing.T) {
+	client := &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}
+	_, err := platform.NewPlatformClient(
+		platform.WithBaseURL("https://localhost"),
+		platform.WithAccount("acc_test", "apisecret"),
+		platform.WithHTTPClient(client),
+		platform.WithMinTLSVersion(tls.VersionTLS13),
+	)
+	if err == nil {
+		t.Error("accepted custom RoundTripper")
+	}
+}
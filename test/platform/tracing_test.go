@@ -0,0 +1,97 @@
+package platform
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// newTraceServer records the traceparent header of the last request
+func newTraceServer(status int, traceparent *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*traceparent = r.Header.Get(platform.TraceparentHeader)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+}
+
+// TestTracer checks that a span is started per call, continues the caller's trace and is propagated
+func TestTracer(t *testing.T) {
+	var traceparent string
+	tps := newTraceServer(http.StatusNotFound, &traceparent)
+	defer tps.Close()
+
+	var spans []platform.FinishedSpan
+	tracer := platform.NewSimpleTracer(func(span platform.FinishedSpan) {
+		spans = append(spans, span)
+	})
+	tpc := newClient(t, tps.URL, platform.WithTracer(tracer))
+
+	parent, err := platform.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	ctx := platform.ContextWithSpanContext(context.Background(), parent)
+	if _, err := tpc.GetWithdrawalContext(ctx, "wd_test"); err == nil {
+		t.Fatal("failed to fail")
+	}
+
+	if len(spans) != 1 {
+		t.Fatalf("Incorrect Spans: %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "platform.GetWithdrawal" || span.Parent != parent || span.Context.TraceID != parent.TraceID {
+		t.Errorf("Incorrect Span: %+v", span)
+	}
+	if traceparent != span.Context.Traceparent() {
+		t.Errorf("Incorrect traceparent: %s, want %s", traceparent, span.Context.Traceparent())
+	}
+	if span.Attributes[platform.AttributeEndpoint] != "GetWithdrawal" ||
+		span.Attributes[platform.AttributeAccount] != "acc_test" ||
+		span.Attributes[platform.AttributeStatusCode] != http.StatusNotFound ||
+		span.Err == nil {
+		t.Errorf("Incorrect Attributes: %+v", span)
+	}
+}
+
+// TestTraceparent_NoTracer checks that the caller's traceparent is propagated without a Tracer
+func TestTraceparent_NoTracer(t *testing.T) {
+	var traceparent string
+	tps := newTraceServer(http.StatusOK, &traceparent)
+	defer tps.Close()
+
+	const incoming = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+	parent, _ := platform.ParseTraceparent(incoming)
+	tpc := newClient(t, tps.URL)
+	if _, err := tpc.AccountBalanceContext(platform.ContextWithSpanContext(context.Background(), parent)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if traceparent != incoming {
+		t.Errorf("Incorrect traceparent: %s", traceparent)
+	}
+
+	if _, err := tpc.AccountBalance(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if traceparent != "" {
+		t.Errorf("traceparent sent without a trace: %s", traceparent)
+	}
+}
+
+// TestParseTraceparentFail checks that malformed traceparent values are rejected
+func TestParseTraceparentFail(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+	} {
+		if _, err := platform.ParseTraceparent(value); err == nil {
+			t.Errorf("accepted %q", value)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package platform
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	platform "github.com/SachinMeier/platform-client-go/platform"
+)
+
+// testInvoice is a BOLT 11 invoice from the specification
+const testInvoice = "lnbc2500u1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpuaztrnwngzn3kdzw5hydlzf03qdgm2hdq27cqv3agm2awhz5se903vruatfhq77w3ls4evs3ch9zw97j25emudupq63nyw24cg27h2rspfj9srp"
+
+// TestWireDump checks that requests and responses are dumped with secrets redacted
+func TestWireDump(t *testing.T) {
+	tps := newServer(http.StatusOK, []byte(`{"url": "https://example.com/hook", "secret": "whsec_1", "enabled": true}`))
+	defer tps.Close()
+
+	var buf bytes.Buffer
+	tpc := newClient(t, tps.URL, platform.WithWireDump(&buf, platform.WireDumpOptions{}))
+	webhook, err := tpc.SubscribeToWebhook("https://example.com/hook")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if webhook.Secret != "whsec_1" {
+		t.Errorf("Incorrect Secret: %q", webhook.Secret)
+	}
+
+	dump := buf.String()
+	for _, want := range []string{
+		">>> SubscribeToWebhook attempt 1 for acc_test",
+		"POST /accounts/acc_test/webhooks/ HTTP/1.1",
+		"Authorization: [REDACTED]",
+		`{"url":"https://example.com/hook"}`,
+		"<<< 200 OK",
+		`"secret":"[REDACTED]"`,
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump has no %q:\n%s", want, dump)
+		}
+	}
+	for _, secret := range []string{"whsec_1", "YXBpc2VjcmV0OmFwaXNlY3JldA=="} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("dump has secret %q:\n%s", secret, dump)
+		}
+	}
+}
+
+// TestWireDump_Invoices checks optional invoice redaction, and that every attempt is dumped
+func TestWireDump_Invoices(t *testing.T) {
+	var hits int32
+	tps := newFlakyServer(1, http.StatusServiceUnavailable, []byte(`{"id": "wd_1", "amount": 100}`), &hits)
+	defer tps.Close()
+
+	for _, redact := range []bool{false, true} {
+		var buf bytes.Buffer
+		atomic.StoreInt32(&hits, 0)
+		tpc := newClient(t, tps.URL,
+			platform.WithRetryPolicy(fastRetryPolicy()),
+			platform.WithWireDump(&buf, platform.WireDumpOptions{RedactInvoices: redact}),
+		)
+		if _, err := tpc.InitiateWithdrawal(platform.Sats(100), testInvoice, platform.BTC, platform.LN, platform.Sats(10)); err != nil {
+			t.Fatal(err.Error())
+		}
+		dump := buf.String()
+		if strings.Contains(dump, testInvoice) == redact {
+			t.Errorf("invoice redacted: %t, want %t:\n%s", !redact, redact, dump)
+		}
+		if !strings.Contains(dump, "<<< 503 Service Unavailable") || !strings.Contains(dump, ">>> InitiateWithdrawal attempt 2") {
+			t.Errorf("dump has no retry:\n%s", dump)
+		}
+	}
+}